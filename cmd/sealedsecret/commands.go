@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	ssv1alpha1 "github.com/bitnami-labs/sealed-secrets/pkg/apis/sealedsecrets/v1alpha1"
+	"github.com/jifwin/terraform-provider-sealedsecret/internal/k8s"
+	"github.com/jifwin/terraform-provider-sealedsecret/internal/kubeseal"
+	certUtil "k8s.io/client-go/util/cert"
+	sigsyaml "sigs.k8s.io/yaml"
+)
+
+func newFlagSet(name string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: sealedsecret %s [flags]\n\nFlags:\n", name)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+func runSeal(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	var opts sealingOptions
+	var file string
+	var nameSuffixHash bool
+	fs := newFlagSet("seal", stderr)
+	opts.register(fs)
+	fs.StringVar(&file, "f", "-", "Secret manifest in YAML or JSON, - reads stdin")
+	fs.BoolVar(&nameSuffixHash, "name-suffix-hash", false, "append a HMAC of the secret keyed with $SEALEDSECRET_HMAC_KEY to its name and seal it namespace-wide, like name_suffix_hash")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	content, err := readInput(file, stdin)
+	if err != nil {
+		return err
+	}
+	manifest, err := k8s.ParseSecretManifest(content)
+	if err != nil {
+		return err
+	}
+	scope, err := kubeseal.ParseScope(opts.scope)
+	if err != nil {
+		return err
+	}
+	if nameSuffixHash {
+		key := os.Getenv("SEALEDSECRET_HMAC_KEY")
+		if key == "" {
+			return errors.New("-name-suffix-hash requires SEALEDSECRET_HMAC_KEY to be set")
+		}
+		if manifest.Name, err = k8s.HashedName(manifest, []byte(key)); err != nil {
+			return err
+		}
+		scope = ssv1alpha1.NamespaceWideScope
+	}
+	secret, err := k8s.CreateSecret(manifest)
+	if err != nil {
+		return err
+	}
+	kubeseal.SetScope(&secret, scope)
+
+	pk, err := opts.publicKey(ctx, stderr)
+	if err != nil {
+		return err
+	}
+	sealed, err := kubeseal.SealSecret(secret, pk)
+	if err != nil {
+		return err
+	}
+	_, err = stdout.Write(sealed)
+	return err
+}
+
+func runRaw(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	var opts sealingOptions
+	var file, name, namespace string
+	fs := newFlagSet("raw", stderr)
+	opts.register(fs)
+	fs.StringVar(&file, "from-file", "-", "file holding the value, - reads stdin")
+	fs.StringVar(&name, "name", "", "name of the secret, not needed for the namespace-wide and cluster-wide scopes")
+	fs.StringVar(&namespace, "namespace", "", "namespace of the secret, not needed for the cluster-wide scope")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	scope, err := kubeseal.ParseScope(opts.scope)
+	if err != nil {
+		return err
+	}
+	if namespace == "" && scope != ssv1alpha1.ClusterWideScope {
+		return errors.New("-namespace is required unless the scope is cluster-wide")
+	}
+	if name == "" && scope == ssv1alpha1.StrictScope {
+		return errors.New("-name is required for the strict scope")
+	}
+	value, err := readInput(file, stdin)
+	if err != nil {
+		return err
+	}
+	pk, err := opts.publicKey(ctx, stderr)
+	if err != nil {
+		return err
+	}
+	sealed, err := kubeseal.SealValue(pk, namespace, name, scope, value)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(stdout, sealed)
+	return err
+}
+
+func runCert(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	var opts clusterOptions
+	var all bool
+	fs := newFlagSet("cert", stderr)
+	opts.register(fs)
+	fs.BoolVar(&all, "all", false, "print the certificates of all keys, oldest first")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	certs, err := opts.certificates(ctx)
+	if err != nil {
+		return err
+	}
+	if !all {
+		selected, err := kubeseal.SelectCert(certs, opts.certFingerprint)
+		if err != nil {
+			return err
+		}
+		certs = []*x509.Certificate{selected}
+	}
+	for _, c := range certs {
+		fmt.Fprintf(stderr, "%s, valid until %s\n", kubeseal.Fingerprint(c.PublicKey.(*rsa.PublicKey)), c.NotAfter.UTC().Format("2006-01-02"))
+		if err := pem.Encode(stdout, &pem.Block{Type: certUtil.CertificateBlockType, Bytes: c.Raw}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runVerify(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	var opts clusterOptions
+	var file string
+	fs := newFlagSet("verify", stderr)
+	opts.register(fs)
+	fs.StringVar(&file, "f", "-", "SealedSecret manifest, - reads stdin")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	content, err := readInput(file, stdin)
+	if err != nil {
+		return err
+	}
+	if _, err := k8s.DecodeSealedSecret(content); err != nil {
+		return err
+	}
+	c, err := opts.client()
+	if err != nil {
+		return err
+	}
+	if err := kubeseal.Verify(ctx, c, opts.controllerName, opts.controllerNamespace, content); err != nil {
+		return err
+	}
+	fmt.Fprintln(stdout, "the controller can unseal the sealed secret")
+	return nil
+}
+
+// inspection is what inspect prints, the values stay sealed.
+type inspection struct {
+	Name      string   `json:"name"`
+	Namespace string   `json:"namespace"`
+	Scope     string   `json:"scope"`
+	Type      string   `json:"type"`
+	Keys      []string `json:"keys"`
+}
+
+func runInspect(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	var file string
+	fs := newFlagSet("inspect", stderr)
+	fs.StringVar(&file, "f", "-", "SealedSecret manifest, - reads stdin")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	content, err := readInput(file, stdin)
+	if err != nil {
+		return err
+	}
+	var sealed ssv1alpha1.SealedSecret
+	if err := sigsyaml.Unmarshal(content, &sealed); err != nil {
+		return fmt.Errorf("unable to parse the sealed secret: %w", err)
+	}
+	if sealed.Kind != "SealedSecret" {
+		return fmt.Errorf("expected a SealedSecret, got %s", sealed.Kind)
+	}
+
+	scope := ssv1alpha1.SecretScope(&sealed)
+	out := inspection{
+		Name:      sealed.Name,
+		Namespace: sealed.Namespace,
+		Scope:     scope.String(),
+		Type:      string(sealed.Spec.Template.Type),
+		Keys:      make([]string, 0, len(sealed.Spec.EncryptedData)),
+	}
+	for key := range sealed.Spec.EncryptedData {
+		out.Keys = append(out.Keys, key)
+	}
+	sort.Strings(out.Keys)
+	b, err := sigsyaml.Marshal(out)
+	if err != nil {
+		return err
+	}
+	_, err = stdout.Write(b)
+	return err
+}
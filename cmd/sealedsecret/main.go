@@ -0,0 +1,56 @@
+// Command sealedsecret seals, verifies and inspects secrets outside Terraform with the same code as the provider,
+// so its output matches the yaml_content of the sealedsecret resource.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+const usage = `Usage: sealedsecret <command> [flags]
+
+Commands:
+  seal     seal a Secret manifest into a SealedSecret manifest
+  raw      seal a single value, like kubeseal --raw
+  cert     print the certificate secrets are sealed with
+  verify   ask the controller whether it can unseal a SealedSecret manifest
+  inspect  print the metadata of a SealedSecret manifest
+
+Run sealedsecret <command> -h for the flags of a command.
+`
+
+type command func(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) error
+
+var commands = map[string]command{
+	"seal":    runSeal,
+	"raw":     runRaw,
+	"cert":    runCert,
+	"verify":  runVerify,
+	"inspect": runInspect,
+}
+
+func main() {
+	if err := run(context.Background(), os.Args[1:], os.Stdin, os.Stdout, os.Stderr); err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintln(os.Stderr, "error:", err)
+		}
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return flag.ErrHelp
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprint(stderr, usage)
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+	return cmd(ctx, args[1:], stdin, stdout, stderr)
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jifwin/terraform-provider-sealedsecret/internal/acctest"
+	"github.com/jifwin/terraform-provider-sealedsecret/internal/kubeseal"
+	"github.com/stretchr/testify/assert"
+)
+
+const secretManifest = `apiVersion: v1
+kind: Secret
+metadata:
+  name: db
+  namespace: default
+stringData:
+  password: secret_aaa
+`
+
+// writeKubeconfig points a kubeconfig at the fake cluster and returns the flags to use it.
+func writeKubeconfig(t *testing.T, cluster *acctest.Cluster) []string {
+	p := filepath.Join(t.TempDir(), "kubeconfig")
+	content := fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: fake
+  cluster:
+    server: %s
+contexts:
+- name: fake
+  context:
+    cluster: fake
+    user: fake
+users:
+- name: fake
+  user:
+    token: token_aaa
+current-context: fake
+`, cluster.Server.URL)
+	if err := os.WriteFile(p, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return []string{"-kubeconfig", p, "-controller-name", acctest.ControllerName, "-controller-namespace", acctest.ControllerNamespace}
+}
+
+func runCommand(t *testing.T, stdin string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	err := run(context.Background(), args, strings.NewReader(stdin), &stdout, &stderr)
+	t.Log(stderr.String())
+	return stdout.String(), err
+}
+
+func TestSealAndVerify(t *testing.T) {
+	cluster := acctest.NewCluster(t)
+	flags := writeKubeconfig(t, cluster)
+
+	sealed, err := runCommand(t, secretManifest, append([]string{"seal"}, flags...)...)
+	assert.Nil(t, err)
+	secret, err := cluster.Unseal([]byte(sealed))
+	assert.Nil(t, err)
+	assert.Equal(t, "secret_aaa", string(secret.Data["password"]))
+
+	out, err := runCommand(t, sealed, append([]string{"verify"}, flags...)...)
+	assert.Nil(t, err)
+	assert.Contains(t, out, "can unseal")
+
+	other := strings.Replace(sealed, "name: db", "name: other", 1)
+	_, err = runCommand(t, other, append([]string{"verify"}, flags...)...)
+	assert.ErrorIs(t, err, kubeseal.ErrCannotUnseal)
+
+	out, err = runCommand(t, sealed, "inspect")
+	assert.Nil(t, err)
+	assert.Equal(t, "keys:\n- password\nname: db\nnamespace: default\nscope: strict\ntype: Opaque\n", out)
+}
+
+func TestSealNameSuffixHash(t *testing.T) {
+	cluster := acctest.NewCluster(t)
+	certFile := filepath.Join(t.TempDir(), "cert.pem")
+	assert.Nil(t, os.WriteFile(certFile, cluster.CertPEM(), 0o600))
+
+	t.Setenv("SEALEDSECRET_HMAC_KEY", "")
+	_, err := runCommand(t, secretManifest, "seal", "-cert", certFile, "-name-suffix-hash")
+	assert.ErrorContains(t, err, "SEALEDSECRET_HMAC_KEY")
+
+	t.Setenv("SEALEDSECRET_HMAC_KEY", "key_aaa")
+	sealed, err := runCommand(t, secretManifest, "seal", "-cert", certFile, "-name-suffix-hash")
+	assert.Nil(t, err)
+	out, err := runCommand(t, sealed, "inspect")
+	assert.Nil(t, err)
+	assert.Contains(t, out, "scope: namespace-wide")
+	assert.Regexp(t, `name: db-[a-z0-9]{10}\n`, out)
+}
+
+func TestSealCertURL(t *testing.T) {
+	cluster := acctest.NewCluster(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(cluster.CertPEM())
+	}))
+	defer server.Close()
+
+	_, err := runCommand(t, secretManifest, "seal", "-cert", server.URL+"/cert.pem")
+	assert.ErrorIs(t, err, kubeseal.ErrPlainHTTP)
+
+	sealed, err := runCommand(t, secretManifest, "seal", "-cert", server.URL+"/cert.pem", "-cert-allow-http")
+	assert.Nil(t, err)
+	secret, err := cluster.Unseal([]byte(sealed))
+	assert.Nil(t, err)
+	assert.Equal(t, "secret_aaa", string(secret.Data["password"]))
+}
+
+func TestRaw(t *testing.T) {
+	cluster := acctest.NewCluster(t)
+	certFile := filepath.Join(t.TempDir(), "cert.pem")
+	assert.Nil(t, os.WriteFile(certFile, cluster.CertPEM(), 0o600))
+
+	out, err := runCommand(t, "value_aaa", "raw", "-cert", certFile, "-namespace", "default", "-name", "db")
+	assert.Nil(t, err)
+	secret, err := cluster.Unseal([]byte(`apiVersion: bitnami.com/v1alpha1
+kind: SealedSecret
+metadata:
+  name: db
+  namespace: default
+spec:
+  encryptedData:
+    password: ` + out + "\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, "value_aaa", string(secret.Data["password"]))
+
+	_, err = runCommand(t, "value_aaa", "raw", "-cert", certFile, "-namespace", "default")
+	assert.EqualError(t, err, "-name is required for the strict scope")
+}
+
+func TestCert(t *testing.T) {
+	cluster := acctest.NewCluster(t)
+	cluster.RotateKey(t)
+	flags := writeKubeconfig(t, cluster)
+
+	out, err := runCommand(t, "", append([]string{"cert"}, flags...)...)
+	assert.Nil(t, err)
+	assert.Equal(t, string(cluster.CertPEM()), out)
+
+	out, err = runCommand(t, "", append([]string{"cert", "-all"}, flags...)...)
+	assert.Nil(t, err)
+	certs, err := kubeseal.ParseCerts([]byte(out))
+	assert.Nil(t, err)
+	assert.Len(t, certs, 2)
+
+	_, err = runCommand(t, "", "unknown")
+	assert.EqualError(t, err, `unknown command "unknown"`)
+}
+
+func TestKubeconfigExecAndProxy(t *testing.T) {
+	cluster := acctest.NewCluster(t)
+	target, err := url.Parse(cluster.Server.URL)
+	assert.Nil(t, err)
+	// kubeconfig credentials are only sent to TLS servers
+	var authorization []string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = append(authorization, r.Header.Get("Authorization"))
+		httputil.NewSingleHostReverseProxy(target).ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	var tunnels []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		tunnels = append(tunnels, r.Host)
+		upstream, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		defer upstream.Close()
+		w.WriteHeader(http.StatusOK)
+		conn, _, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		go func() { _, _ = io.Copy(upstream, conn) }()
+		_, _ = io.Copy(conn, upstream)
+	}))
+	defer proxy.Close()
+
+	p := filepath.Join(t.TempDir(), "kubeconfig")
+	content := fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: fake
+  cluster:
+    server: %s
+    insecure-skip-tls-verify: true
+    proxy-url: %s
+contexts:
+- name: fake
+  context:
+    cluster: fake
+    user: fake
+users:
+- name: fake
+  user:
+    exec:
+      apiVersion: client.authentication.k8s.io/v1
+      interactiveMode: Never
+      command: sh
+      args:
+      - -c
+      - |-
+        echo '{"apiVersion":"client.authentication.k8s.io/v1","kind":"ExecCredential","status":{"token":"exec_token_aaa"}}'
+current-context: fake
+`, server.URL, proxy.URL)
+	assert.Nil(t, os.WriteFile(p, []byte(content), 0o600))
+
+	out, err := runCommand(t, "", "cert", "-kubeconfig", p, "-controller-name", acctest.ControllerName, "-controller-namespace", acctest.ControllerNamespace)
+	assert.Nil(t, err)
+	assert.Equal(t, string(cluster.CertPEM()), out)
+	assert.Equal(t, []string{server.Listener.Addr().String()}, tunnels, "the kubeconfig proxy-url is used")
+	if assert.NotEmpty(t, authorization) {
+		for _, a := range authorization {
+			assert.Equal(t, "Bearer exec_token_aaa", a, "the token of the exec plugin is sent")
+		}
+	}
+}
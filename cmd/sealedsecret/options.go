@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jifwin/terraform-provider-sealedsecret/internal/k8s"
+	"github.com/jifwin/terraform-provider-sealedsecret/internal/kubeseal"
+	"k8s.io/client-go/tools/clientcmd"
+	// registers the oidc auth provider of kubeconfigs, exec plugins are supported by client-go itself
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+)
+
+// clusterOptions are the flags of the commands that talk to the controller. The defaults are the provider's.
+type clusterOptions struct {
+	kubeconfig          string
+	context             string
+	controllerName      string
+	controllerNamespace string
+	certFingerprint     string
+	portForward         bool
+}
+
+func (o *clusterOptions) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.kubeconfig, "kubeconfig", "", "path to the kubeconfig, defaults to $KUBECONFIG or ~/.kube/config")
+	fs.StringVar(&o.context, "context", "", "kubeconfig context to use, defaults to the current one")
+	fs.StringVar(&o.controllerName, "controller-name", kubeseal.DefaultControllerName, "name of the sealed-secrets controller")
+	fs.StringVar(&o.controllerNamespace, "controller-namespace", kubeseal.DefaultControllerNamespace, "namespace of the sealed-secrets controller")
+	fs.StringVar(&o.certFingerprint, "certificate-fingerprint", "", "seal with the certificate of this fingerprint instead of the newest one")
+	fs.BoolVar(&o.portForward, "port-forward", false, "reach the controller through a port-forward to its pod instead of the service proxy")
+}
+
+func (o *clusterOptions) client() (*k8s.Client, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = o.kubeconfig
+	restCfg, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{CurrentContext: o.context}).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("unable to load the kubeconfig: %w", err)
+	}
+	return k8s.NewClientForConfig(restCfg, o.portForward)
+}
+
+func (o *clusterOptions) certificates(ctx context.Context) ([]*x509.Certificate, error) {
+	c, err := o.client()
+	if err != nil {
+		return nil, err
+	}
+	return kubeseal.FetchCerts(c, o.controllerName, o.controllerNamespace)(ctx)
+}
+
+// sealingOptions are the flags of the commands that seal, they can work offline with a certificate file.
+type sealingOptions struct {
+	clusterOptions
+	certFile string
+	certHTTP bool
+	scope    string
+}
+
+func (o *sealingOptions) register(fs *flag.FlagSet) {
+	o.clusterOptions.register(fs)
+	fs.StringVar(&o.certFile, "cert", "", "seal with the certificate in this PEM file or at this https URL instead of fetching it from the controller")
+	fs.BoolVar(&o.certHTTP, "cert-allow-http", false, "accept an http URL for -cert, anyone on the network path could replace the certificate")
+	fs.StringVar(&o.scope, "scope", "strict", "sealing scope: strict, namespace-wide or cluster-wide")
+}
+
+// publicKey selects the key to seal with like the provider does. An expiring certificate is reported on stderr.
+func (o *sealingOptions) publicKey(ctx context.Context, stderr io.Writer) (*rsa.PublicKey, error) {
+	certs := o.certificates
+	if o.certFile != "" {
+		var err error
+		if certs, err = kubeseal.FetchCertsFromURL(kubeseal.CertURL{URL: o.certFile, AllowHTTP: o.certHTTP}); err != nil {
+			return nil, err
+		}
+	}
+	pk, err := kubeseal.SelectPK(certs, o.certFingerprint, kubeseal.ValidityPolicy{})(ctx)
+	var expiry *kubeseal.ExpiryError
+	if pk != nil && errors.As(err, &expiry) {
+		fmt.Fprintln(stderr, "warning:", expiry)
+		return pk, nil
+	}
+	return pk, err
+}
+
+// readInput reads the file, or stdin when it is empty or -.
+func readInput(file string, stdin io.Reader) ([]byte, error) {
+	if file == "" || file == "-" {
+		return io.ReadAll(stdin)
+	}
+	return os.ReadFile(file)
+}
@@ -45,4 +45,17 @@ variable "k8s_cluster_ca_certificate" {
 }
 variable "k8s_host" {
   type = string
-}
\ No newline at end of file
+}
+resource "sealedsecret" "write_only" {
+  name            = "write-only-secret"
+  namespace       = "default"
+  data_wo         = {
+    "key" : "value"
+  }
+  data_wo_version = 1
+}
+
+resource "sealedsecret_in_cluster" "example" {
+  yaml_content    = sealedsecret.example.yaml_content
+  wait_for_synced = true
+}
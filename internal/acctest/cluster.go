@@ -0,0 +1,450 @@
+// Package acctest provides an in-process fake of a Kubernetes API server running the sealed-secrets controller, so
+// the acceptance tests run offline.
+package acctest
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	ssv1alpha1 "github.com/bitnami-labs/sealed-secrets/pkg/apis/sealedsecrets/v1alpha1"
+	"github.com/jifwin/terraform-provider-sealedsecret/internal/k8s"
+	"github.com/jifwin/terraform-provider-sealedsecret/internal/kubeseal"
+	v1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+	certUtil "k8s.io/client-go/util/cert"
+)
+
+const (
+	ControllerName      = "sealed-secrets-controller"
+	ControllerNamespace = "kube-system"
+
+	controllerProxy = "/api/v1/namespaces/" + ControllerNamespace + "/services/http:" + ControllerName + ":http/proxy"
+)
+
+// Cluster serves the parts of the Kubernetes API the provider uses: the controller's /v1/cert.pem, /v1/verify and
+// /v1/rotate behind the service proxy, SealedSecrets, the Secrets the fake controller unseals them into and the
+// Secrets holding its keys.
+type Cluster struct {
+	Server *httptest.Server
+
+	mu              sync.Mutex
+	serviceDisabled bool
+	keys            []*rsa.PrivateKey
+	certs           []*x509.Certificate
+	sealedSecrets   map[string]*unstructured.Unstructured
+	secrets         map[string]*v1.Secret
+}
+
+// NewCluster starts a fake cluster with one sealing key, it is stopped when the test ends.
+func NewCluster(t testing.TB) *Cluster {
+	c := &Cluster{
+		sealedSecrets: map[string]*unstructured.Unstructured{},
+		secrets:       map[string]*v1.Secret{},
+	}
+	c.RotateKey(t)
+
+	proxy := controllerProxy
+	sealedSecrets := "/apis/bitnami.com/v1alpha1/namespaces/{namespace}/sealedsecrets/{name}"
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+proxy+"/v1/cert.pem", c.service(c.serveCert))
+	mux.HandleFunc("POST "+proxy+"/v1/verify", c.service(c.serveVerify))
+	mux.HandleFunc("POST "+proxy+"/v1/rotate", c.service(c.serveRotate))
+	mux.HandleFunc("GET "+sealedSecrets, c.serveGetSealedSecret)
+	mux.HandleFunc("PATCH "+sealedSecrets, c.serveApplySealedSecret)
+	mux.HandleFunc("DELETE "+sealedSecrets, c.serveDeleteSealedSecret)
+	mux.HandleFunc("GET /api/v1/namespaces/{namespace}/secrets/{name}", c.serveGetSecret)
+	mux.HandleFunc("GET /api/v1/namespaces/{namespace}/secrets", c.serveListSecrets)
+	c.Server = httptest.NewServer(mux)
+	t.Cleanup(c.Server.Close)
+	return c
+}
+
+// RotateKey adds a new sealing key, like the controller does every 30 days. Old keys can still unseal.
+func (c *Cluster) RotateKey(t testing.TB) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(now.UnixNano()),
+		Subject:      pkix.Name{CommonName: "sealed-secret"},
+		NotBefore:    now,
+		NotAfter:     now.Add(10 * 365 * 24 * time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.keys = append(c.keys, key)
+	c.certs = append(c.certs, cert)
+}
+
+// DisableService makes the controller's HTTP service unavailable, like in clusters where it is turned off.
+func (c *Cluster) DisableService() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.serviceDisabled = true
+}
+
+// ProviderConfig returns the provider block connecting to the fake cluster, with additional attribute lines.
+func (c *Cluster) ProviderConfig(attributes ...string) string {
+	return fmt.Sprintf(`
+provider "sealedsecret" {
+  controller_name      = %q
+  controller_namespace = %q
+  %s
+  kubernetes {
+    host                   = %q
+    cluster_ca_certificate = ""
+  }
+}
+`, ControllerName, ControllerNamespace, strings.Join(attributes, "\n  "), c.Server.URL)
+}
+
+// CertPEM returns the certificate of the latest key.
+func (c *Cluster) CertPEM() []byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return pem.EncodeToMemory(&pem.Block{Type: certUtil.CertificateBlockType, Bytes: c.certs[len(c.certs)-1].Raw})
+}
+
+// Unseal decrypts a SealedSecret manifest with any of the keys.
+func (c *Cluster) Unseal(manifest []byte) (*v1.Secret, error) {
+	object, err := runtime.Decode(scheme.Codecs.UniversalDecoder(ssv1alpha1.SchemeGroupVersion), manifest)
+	if err != nil {
+		return nil, err
+	}
+	ss, ok := object.(*ssv1alpha1.SealedSecret)
+	if !ok {
+		return nil, fmt.Errorf("expected a SealedSecret, got %T", object)
+	}
+	return ss.Unseal(scheme.Codecs, c.privateKeys())
+}
+
+// SealedWithLatestKey reports whether only the latest key can unseal the manifest.
+func (c *Cluster) SealedWithLatestKey(manifest []byte) bool {
+	object, err := runtime.Decode(scheme.Codecs.UniversalDecoder(ssv1alpha1.SchemeGroupVersion), manifest)
+	if err != nil {
+		return false
+	}
+	ss, ok := object.(*ssv1alpha1.SealedSecret)
+	if !ok {
+		return false
+	}
+	c.mu.Lock()
+	latest := map[string]*rsa.PrivateKey{"latest": c.keys[len(c.keys)-1]}
+	c.mu.Unlock()
+	_, err = ss.Unseal(scheme.Codecs, latest)
+	return err == nil
+}
+
+// Verify asks the controller whether it can unseal the manifest, like kubeseal --validate.
+func (c *Cluster) Verify(manifest []byte) error {
+	resp, err := c.postController("/v1/verify", manifest)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("the controller cannot unseal the manifest: %s", resp.Status)
+	}
+	return nil
+}
+
+// Rotate asks the controller to re-encrypt the manifest with the latest key, like kubeseal --re-encrypt.
+func (c *Cluster) Rotate(manifest []byte) ([]byte, error) {
+	resp, err := c.postController("/v1/rotate", manifest)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("the controller cannot re-encrypt the manifest: %s: %s", resp.Status, body)
+	}
+	return body, nil
+}
+
+// ApplySealedSecret applies a SealedSecret manifest behind the provider's back, like kubectl apply.
+func (c *Cluster) ApplySealedSecret(t testing.TB, manifest []byte) {
+	obj, err := k8s.DecodeSealedSecret(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := json.Marshal(obj.Object)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := fmt.Sprintf("%s/apis/bitnami.com/v1alpha1/namespaces/%s/sealedsecrets/%s", c.Server.URL, obj.GetNamespace(), obj.GetName())
+	req, err := http.NewRequest(http.MethodPatch, u, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.Server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unable to apply the SealedSecret: %s", resp.Status)
+	}
+}
+
+// DeleteSealedSecret removes a SealedSecret and its Secret behind the provider's back.
+func (c *Cluster) DeleteSealedSecret(namespace, name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.sealedSecrets, namespace+"/"+name)
+	delete(c.secrets, namespace+"/"+name)
+}
+
+func (c *Cluster) postController(path string, body []byte) (*http.Response, error) {
+	return c.Server.Client().Post(c.Server.URL+controllerProxy+path, "application/yaml", bytes.NewReader(body))
+}
+
+// Secret returns the Secret the fake controller unsealed, if any.
+func (c *Cluster) Secret(namespace, name string) (*v1.Secret, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.secrets[namespace+"/"+name]
+	return s, ok
+}
+
+func (c *Cluster) privateKeys() map[string]*rsa.PrivateKey {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	keys := make(map[string]*rsa.PrivateKey, len(c.keys))
+	for i, k := range c.keys {
+		keys[fmt.Sprint(i)] = k
+	}
+	return keys
+}
+
+// Fingerprints returns the fingerprints of all keys, oldest first.
+func (c *Cluster) Fingerprints() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fingerprints := make([]string, 0, len(c.certs))
+	for _, cert := range c.certs {
+		fingerprints = append(fingerprints, kubeseal.Fingerprint(cert.PublicKey.(*rsa.PublicKey)))
+	}
+	return fingerprints
+}
+
+// service answers like the API server's proxy when the controller's service is disabled.
+func (c *Cluster) service(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c.mu.Lock()
+		disabled := c.serviceDisabled
+		c.mu.Unlock()
+		if disabled {
+			http.Error(w, "no endpoints available for service", http.StatusServiceUnavailable)
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// serveCert writes the certificates of all keys, oldest first, like the controller does.
+func (c *Cluster) serveCert(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	var out []byte
+	for _, cert := range c.certs {
+		out = append(out, pem.EncodeToMemory(&pem.Block{Type: certUtil.CertificateBlockType, Bytes: cert.Raw})...)
+	}
+	c.mu.Unlock()
+	w.Header().Set("Content-Type", "application/x-pem-file")
+	_, _ = w.Write(out)
+}
+
+func (c *Cluster) serveVerify(w http.ResponseWriter, r *http.Request) {
+	content, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if _, err := c.Unseal(content); err != nil {
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (c *Cluster) serveRotate(w http.ResponseWriter, r *http.Request) {
+	content, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	secret, err := c.Unseal(content)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	c.mu.Lock()
+	latest := &c.keys[len(c.keys)-1].PublicKey
+	c.mu.Unlock()
+	resealed, err := ssv1alpha1.NewSealedSecret(scheme.Codecs, latest, secret)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	resealed.APIVersion, resealed.Kind = ssv1alpha1.SchemeGroupVersion.String(), "SealedSecret"
+	writeJSON(w, http.StatusOK, resealed)
+}
+
+func (c *Cluster) serveGetSealedSecret(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	obj, ok := c.sealedSecrets[key(r)]
+	c.mu.Unlock()
+	if !ok {
+		writeNotFound(w, ssv1alpha1.Resource("sealedsecrets"), r.PathValue("name"))
+		return
+	}
+	writeJSON(w, http.StatusOK, obj.Object)
+}
+
+// serveApplySealedSecret handles server-side apply and unseals the object right away, like the controller would.
+func (c *Cluster) serveApplySealedSecret(w http.ResponseWriter, r *http.Request) {
+	obj := &unstructured.Unstructured{}
+	if err := json.NewDecoder(r.Body).Decode(&obj.Object); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	c.mu.Lock()
+	generation := int64(1)
+	if old, ok := c.sealedSecrets[key(r)]; ok {
+		generation = old.GetGeneration()
+		if !equalSpec(old, obj) {
+			generation++
+		}
+	}
+	c.mu.Unlock()
+	obj.SetGeneration(generation)
+
+	condition := map[string]interface{}{"type": "Synced", "status": "True"}
+	manifest, _ := json.Marshal(obj.Object)
+	secret, err := c.Unseal(manifest)
+	if err != nil {
+		condition = map[string]interface{}{"type": "Synced", "status": "False", "reason": "ErrUnsealFailed", "message": err.Error()}
+	}
+	_ = unstructured.SetNestedField(obj.Object, generation, "status", "observedGeneration")
+	_ = unstructured.SetNestedSlice(obj.Object, []interface{}{condition}, "status", "conditions")
+
+	c.mu.Lock()
+	c.sealedSecrets[key(r)] = obj
+	if secret != nil {
+		c.secrets[key(r)] = secret
+	}
+	c.mu.Unlock()
+	writeJSON(w, http.StatusOK, obj.Object)
+}
+
+func (c *Cluster) serveDeleteSealedSecret(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	_, ok := c.sealedSecrets[key(r)]
+	delete(c.sealedSecrets, key(r))
+	// the Secret is owned by the SealedSecret and garbage collected with it
+	delete(c.secrets, key(r))
+	c.mu.Unlock()
+	if !ok {
+		writeNotFound(w, ssv1alpha1.Resource("sealedsecrets"), r.PathValue("name"))
+		return
+	}
+	writeJSON(w, http.StatusOK, &metav1.Status{TypeMeta: metav1.TypeMeta{Kind: "Status", APIVersion: "v1"}, Status: metav1.StatusSuccess})
+}
+
+func (c *Cluster) serveGetSecret(w http.ResponseWriter, r *http.Request) {
+	secret, ok := c.Secret(r.PathValue("namespace"), r.PathValue("name"))
+	if !ok {
+		writeNotFound(w, v1.Resource("secrets"), r.PathValue("name"))
+		return
+	}
+	secret = secret.DeepCopy()
+	secret.APIVersion, secret.Kind = "v1", "Secret"
+	writeJSON(w, http.StatusOK, secret)
+}
+
+// serveListSecrets lists the key Secrets of the controller, every key is labelled active like the controller does.
+// Unsealed Secrets are not listed.
+func (c *Cluster) serveListSecrets(w http.ResponseWriter, r *http.Request) {
+	selector, err := labels.Parse(r.URL.Query().Get("labelSelector"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	list := &v1.SecretList{TypeMeta: metav1.TypeMeta{Kind: "SecretList", APIVersion: "v1"}}
+	c.mu.Lock()
+	for i, cert := range c.certs {
+		secret := v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("sealed-secrets-key%05d", i),
+				Namespace: ControllerNamespace,
+				Labels:    map[string]string{k8s.KeyLabel: "active"},
+			},
+			Type: v1.SecretTypeTLS,
+			Data: map[string][]byte{
+				v1.TLSCertKey: pem.EncodeToMemory(&pem.Block{Type: certUtil.CertificateBlockType, Bytes: cert.Raw}),
+			},
+		}
+		if r.PathValue("namespace") == ControllerNamespace && selector.Matches(labels.Set(secret.Labels)) {
+			list.Items = append(list.Items, secret)
+		}
+	}
+	c.mu.Unlock()
+	writeJSON(w, http.StatusOK, list)
+}
+
+func key(r *http.Request) string {
+	return r.PathValue("namespace") + "/" + r.PathValue("name")
+}
+
+func equalSpec(a, b *unstructured.Unstructured) bool {
+	specA, _ := json.Marshal(a.Object["spec"])
+	specB, _ := json.Marshal(b.Object["spec"])
+	return bytes.Equal(specA, specB)
+}
+
+func writeNotFound(w http.ResponseWriter, resource schema.GroupResource, name string) {
+	status := k8sErrors.NewNotFound(resource, name).ErrStatus
+	status.Kind, status.APIVersion = "Status", "v1"
+	writeJSON(w, http.StatusNotFound, &status)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
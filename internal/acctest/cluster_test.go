@@ -0,0 +1,33 @@
+package acctest
+
+import (
+	"testing"
+
+	"github.com/jifwin/terraform-provider-sealedsecret/internal/k8s"
+	"github.com/jifwin/terraform-provider-sealedsecret/internal/kubeseal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClusterVerifyAndRotate(t *testing.T) {
+	c := NewCluster(t)
+	pk, err := kubeseal.ParsePK(c.CertPEM())
+	assert.Nil(t, err)
+	secret, err := k8s.CreateSecret(&k8s.SecretManifest{Name: "name-aaa", Namespace: "ns-aaa", Type: "Opaque", Data: map[string]string{"key": "value_aaa"}})
+	assert.Nil(t, err)
+	manifest, err := kubeseal.SealSecret(secret, pk)
+	assert.Nil(t, err)
+
+	assert.Nil(t, c.Verify(manifest))
+	assert.NotNil(t, c.Verify([]byte("apiVersion: bitnami.com/v1alpha1\nkind: SealedSecret\nmetadata:\n  name: other\n  namespace: ns-aaa\nspec:\n  encryptedData:\n    key: AAAA\n")))
+
+	c.RotateKey(t)
+	assert.False(t, c.SealedWithLatestKey(manifest))
+	assert.Nil(t, c.Verify(manifest), "old keys can still unseal")
+
+	rotated, err := c.Rotate(manifest)
+	assert.Nil(t, err)
+	assert.True(t, c.SealedWithLatestKey(rotated))
+	unsealed, err := c.Unseal(rotated)
+	assert.Nil(t, err)
+	assert.Equal(t, "value_aaa", string(unsealed.Data["key"]))
+}
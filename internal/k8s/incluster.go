@@ -0,0 +1,40 @@
+package k8s
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// The service account of a pod is mounted at these paths. They are variables so tests can point them elsewhere.
+var (
+	InClusterTokenFile  = "/var/run/secrets/kubernetes.io/serviceaccount/" + v1.ServiceAccountTokenKey
+	InClusterCACertFile = "/var/run/secrets/kubernetes.io/serviceaccount/" + v1.ServiceAccountRootCAKey
+)
+
+var ErrNotInCluster = errors.New("not running in a pod, KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT are not set")
+
+// InCluster fills the unset host, token and CA of cfg from the environment and the service account of the pod, like
+// rest.InClusterConfig does. The token file is re-read periodically, so the rotated tokens of the kubelet are used.
+func InCluster(cfg *Config) error {
+	if cfg.Host == "" {
+		host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+		if host == "" || port == "" {
+			return ErrNotInCluster
+		}
+		cfg.Host = "https://" + net.JoinHostPort(host, port)
+	}
+	if cfg.Token == "" && cfg.TokenFile == "" {
+		if _, err := os.Stat(InClusterTokenFile); err != nil {
+			return fmt.Errorf("unable to read the service account token: %w", err)
+		}
+		cfg.TokenFile = InClusterTokenFile
+	}
+	if len(cfg.ClusterCACert) == 0 && cfg.ClusterCACertFile == "" && !cfg.Insecure {
+		cfg.ClusterCACertFile = InClusterCACertFile
+	}
+	return nil
+}
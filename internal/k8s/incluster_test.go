@@ -0,0 +1,58 @@
+package k8s
+
+import (
+	"context"
+	"encoding/pem"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakePod points the in-cluster settings at the server, with the given service account token.
+func fakePod(t *testing.T, server *httptest.Server, token string) {
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	assert.Nil(t, err)
+	t.Setenv("KUBERNETES_SERVICE_HOST", host)
+	t.Setenv("KUBERNETES_SERVICE_PORT", port)
+
+	dir := t.TempDir()
+	tokenFile, caFile := InClusterTokenFile, InClusterCACertFile
+	t.Cleanup(func() { InClusterTokenFile, InClusterCACertFile = tokenFile, caFile })
+	InClusterTokenFile, InClusterCACertFile = filepath.Join(dir, "token"), filepath.Join(dir, "ca.crt")
+	assert.Nil(t, os.WriteFile(InClusterTokenFile, []byte(token), 0o600))
+	assert.Nil(t, os.WriteFile(InClusterCACertFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0o600))
+}
+
+func TestInCluster(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer sa_token_aaa" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte("cert_aaa"))
+	}))
+	defer server.Close()
+	fakePod(t, server, "sa_token_aaa")
+
+	cfg := &Config{}
+	assert.Nil(t, InCluster(cfg))
+	assert.Equal(t, Config{Host: server.URL, TokenFile: InClusterTokenFile, ClusterCACertFile: InClusterCACertFile}, *cfg)
+	c, err := NewClient(cfg)
+	assert.Nil(t, err)
+	resp, err := c.Get(context.Background(), "controller_aaa", "ns_aaa", "/v1/cert.pem")
+	assert.Nil(t, err)
+	assert.Equal(t, "cert_aaa", string(resp))
+
+	// explicit settings are kept
+	cfg = &Config{Host: "https://host_aaa", Token: "token_aaa", ClusterCACert: []byte("ca_aaa")}
+	assert.Nil(t, InCluster(cfg))
+	assert.Equal(t, Config{Host: "https://host_aaa", Token: "token_aaa", ClusterCACert: []byte("ca_aaa")}, *cfg)
+
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+	assert.ErrorIs(t, InCluster(&Config{}), ErrNotInCluster)
+}
@@ -5,57 +5,138 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"time"
 
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"k8s.io/apimachinery/pkg/util/net"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
 	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/rest"
 )
 
+// frontoff polls often right after a change, when the controller usually reacts, and backs off up to Cap afterwards.
 var frontoff = wait.Backoff{
-	Cap:      3 * time.Minute,
+	Cap:      30 * time.Second,
 	Steps:    10,
-	Duration: 45 * time.Second,
-	Factor:   0.83,
+	Duration: time.Second,
+	Factor:   1.5,
 	Jitter:   0.1,
 }
 
 type Client struct {
-	RestClient *corev1.CoreV1Client
+	RestClient corev1.CoreV1Interface
+	Dynamic    dynamic.Interface
+	// PodDialer makes Get and Post port-forward to a controller pod instead of using the service proxy when set.
+	PodDialer PodDialerFunc
 }
 
+// DefaultTimeout is the request timeout of a Config without one.
+const DefaultTimeout = 10 * time.Second
+
 type Config struct {
 	Host                                 string
 	ClusterCACert, ClientCert, ClientKey []byte
-	Token                                string
-	Transport                            http.RoundTripper
+	// The files are read instead of the PEM data above when set.
+	ClusterCACertFile, ClientCertFile, ClientKeyFile string
+	Token                                            string
+	// TokenFile is read instead of Token and re-read periodically, so rotated tokens are picked up.
+	TokenFile     string
+	ProxyURL      string
+	TLSServerName string
+	Insecure      bool
+	Timeout       time.Duration
+	// ImpersonateUser, ImpersonateGroups and ImpersonateExtra make every request act as another user.
+	ImpersonateUser   string
+	ImpersonateGroups []string
+	ImpersonateExtra  map[string][]string
+	// PortForward reaches the controller through a port-forward to one of its pods instead of the service proxy.
+	PortForward bool
+	Transport   http.RoundTripper
 }
 
 type Clienter interface {
 	Get(ctx context.Context, controllerName, controllerNamespace, path string) ([]byte, error)
 }
 
+type Poster interface {
+	Post(ctx context.Context, controllerName, controllerNamespace, path string, body []byte) ([]byte, error)
+}
+
 func NewClient(cfg *Config) (*Client, error) {
 	restCfg := &rest.Config{
-		Timeout: 10 * time.Second,
+		Timeout: cfg.Timeout,
 	}
 	restCfg.Host = cfg.Host
 	restCfg.CAData = cfg.ClusterCACert
 	restCfg.CertData = cfg.ClientCert
 	restCfg.KeyData = cfg.ClientKey
+	restCfg.CAFile = cfg.ClusterCACertFile
+	restCfg.CertFile = cfg.ClientCertFile
+	restCfg.KeyFile = cfg.ClientKeyFile
 	restCfg.BearerToken = cfg.Token
+	restCfg.BearerTokenFile = cfg.TokenFile
+	restCfg.ServerName = cfg.TLSServerName
+	restCfg.Insecure = cfg.Insecure
+	restCfg.Impersonate = rest.ImpersonationConfig{
+		UserName: cfg.ImpersonateUser,
+		Groups:   cfg.ImpersonateGroups,
+		Extra:    cfg.ImpersonateExtra,
+	}
+	if cfg.ProxyURL != "" {
+		proxyURL, err := url.Parse(cfg.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		if proxyURL.Scheme == "" || proxyURL.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %q: expected scheme://host[:port]", cfg.ProxyURL)
+		}
+		restCfg.Proxy = http.ProxyURL(proxyURL)
+	}
 	if cfg.Transport != nil {
 		restCfg.Transport = cfg.Transport
 	}
+	return NewClientForConfig(restCfg, cfg.PortForward)
+}
+
+// NewClientForConfig creates a client from a rest.Config as loaded from a kubeconfig, so its exec and auth provider
+// plugins and proxy are used as they are. Requests are traced like the ones of NewClient.
+func NewClientForConfig(restCfg *rest.Config, portForward bool) (*Client, error) {
+	restCfg = rest.CopyConfig(restCfg)
+	if restCfg.Timeout == 0 {
+		restCfg.Timeout = DefaultTimeout
+	}
+	restCfg.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+		return traceTransport{next: rt}
+	})
 
 	c, err := corev1.NewForConfig(restCfg)
 	if err != nil {
 		return nil, err
 	}
-	return &Client{RestClient: c}, nil
+	d, err := dynamic.NewForConfig(restCfg)
+	if err != nil {
+		return nil, err
+	}
+	client := &Client{RestClient: c, Dynamic: d}
+	if portForward {
+		if client.PodDialer, err = spdyPodDialer(restCfg, c.RESTClient()); err != nil {
+			return nil, err
+		}
+	}
+	return client, nil
 }
 
 func (c *Client) Get(ctx context.Context, controllerName, controllerNamespace, path string) ([]byte, error) {
+	tflog.SubsystemDebug(ctx, LogSubsystem, "Requesting the controller", map[string]interface{}{
+		"controller":           controllerName,
+		"controller_namespace": controllerNamespace,
+		"path":                 path,
+	})
+	if c.PodDialer != nil {
+		return c.forward(ctx, controllerName, controllerNamespace, http.MethodGet, path, nil)
+	}
 	resp, err := c.RestClient.
 		Services(controllerNamespace).
 		ProxyGet("http", controllerName, "http", path, nil).
@@ -70,3 +151,27 @@ func (c *Client) Get(ctx context.Context, controllerName, controllerNamespace, p
 	}
 	return b, nil
 }
+
+// Post sends body to the controller through the API server's service proxy.
+func (c *Client) Post(ctx context.Context, controllerName, controllerNamespace, path string, body []byte) ([]byte, error) {
+	tflog.SubsystemDebug(ctx, LogSubsystem, "Posting to the controller", map[string]interface{}{
+		"controller":           controllerName,
+		"controller_namespace": controllerNamespace,
+		"path":                 path,
+	})
+	if c.PodDialer != nil {
+		return c.forward(ctx, controllerName, controllerNamespace, http.MethodPost, path, body)
+	}
+	b, err := c.RestClient.RESTClient().Post().
+		Namespace(controllerNamespace).
+		Resource("services").
+		SubResource("proxy").
+		Name(net.JoinSchemeNamePort("http", controllerName, "http")).
+		Suffix(path).
+		Body(body).
+		DoRaw(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("request to k8s cluster failed: %w", err)
+	}
+	return b, nil
+}
@@ -1,12 +1,21 @@
 package k8s
 
 import (
+	"bytes"
 	"context"
+	"encoding/pem"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-log/tflogtest"
 	"github.com/stretchr/testify/assert"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 type roundTripFunc func(req *http.Request) (*http.Response, error)
@@ -35,13 +44,13 @@ func TestGet(t *testing.T) {
 			ExpectedErr:      "",
 		},
 		{
-			Name: "retries logic is working",
+			Name: "transport error is returned",
 			Mock: roundTripFunc(func(req *http.Request) (*http.Response, error) {
 				return nil, nil
 			}),
 			ExpectedResponse:        "",
-			ExpectedErr:             "request to k8s cluster failed: Get \"http://localhost/api/v1/namespaces/controllerNs_aaa/services/http:controllerName_aaa:/proxy/path_aaa?timeout=10s\": http: RoundTripper implementation (*transport.userAgentRoundTripper) returned a nil *Response with a nil error",
-			ExpectedNumberOfRetries: 5,
+			ExpectedErr:             "request to k8s cluster failed: Get \"http://localhost/api/v1/namespaces/controllerNs_aaa/services/http:controllerName_aaa:http/proxy/path_aaa?timeout=10s\": http: RoundTripper implementation (*transport.userAgentRoundTripper) returned a nil *Response with a nil error",
+			ExpectedNumberOfRetries: 1,
 		},
 	}
 
@@ -75,3 +84,118 @@ func TestGet(t *testing.T) {
 		})
 	}
 }
+
+func TestRequestsAreTraced(t *testing.T) {
+	var out bytes.Buffer
+	ctx := tflog.NewSubsystem(tflogtest.RootLogger(context.Background(), &out), LogSubsystem)
+
+	c, err := NewClient(&Config{
+		Host:  "http://127.0.0.1",
+		Token: "token_aaa",
+		Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("cert_aaa"))}, nil
+		}),
+	})
+	assert.Nil(t, err)
+	_, err = c.Get(ctx, "controller_aaa", "ns_aaa", "/v1/cert.pem")
+	assert.Nil(t, err)
+
+	assert.NotContains(t, out.String(), "token_aaa")
+	entries, err := tflogtest.MultilineJSONDecode(&out)
+	assert.Nil(t, err)
+	var traced map[string]interface{}
+	for _, e := range entries {
+		if e["@message"] == "Kubernetes API request" {
+			traced = e
+		}
+	}
+	assert.NotNil(t, traced)
+	assert.Equal(t, "trace", traced["@level"])
+	assert.Equal(t, "GET", traced["method"])
+	assert.Equal(t, float64(http.StatusOK), traced["status"])
+	assert.Contains(t, traced["url"], "/namespaces/ns_aaa/services/http:controller_aaa:http/proxy/v1/cert.pem")
+}
+
+func TestTransportOptions(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token_aaa" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "/slow") {
+			time.Sleep(500 * time.Millisecond)
+		}
+		_, _ = w.Write([]byte("cert_aaa"))
+	})
+	server := httptest.NewTLSServer(handler)
+	defer server.Close()
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.Host
+		handler(w, r)
+	}))
+	defer proxy.Close()
+
+	dir := t.TempDir()
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	caFile, tokenFile := filepath.Join(dir, "ca.crt"), filepath.Join(dir, "token")
+	assert.Nil(t, os.WriteFile(caFile, caPEM, 0o600))
+	assert.Nil(t, os.WriteFile(tokenFile, []byte("token_aaa"), 0o600))
+	// the certificate of the test server is valid for example.com but not for localhost
+	localhost := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+
+	tests := []struct {
+		name        string
+		cfg         Config
+		path        string
+		expectedErr string
+	}{
+		{name: "ca and token files", cfg: Config{Host: server.URL, ClusterCACertFile: caFile, TokenFile: tokenFile}},
+		{name: "untrusted server", cfg: Config{Host: server.URL, Token: "token_aaa"}, expectedErr: "certificate signed by unknown authority"},
+		{name: "insecure", cfg: Config{Host: server.URL, Token: "token_aaa", Insecure: true}},
+		{name: "wrong server name", cfg: Config{Host: localhost, ClusterCACert: caPEM, Token: "token_aaa"}, expectedErr: "not localhost"},
+		{name: "tls server name", cfg: Config{Host: localhost, ClusterCACert: caPEM, Token: "token_aaa", TLSServerName: "example.com"}},
+		{name: "timeout", cfg: Config{Host: server.URL, ClusterCACert: caPEM, Token: "token_aaa", Timeout: 50 * time.Millisecond}, path: "/slow", expectedErr: "Client.Timeout exceeded"},
+		{name: "proxy", cfg: Config{Host: "http://cluster.invalid", Token: "token_aaa", ProxyURL: proxy.URL}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewClient(&tc.cfg)
+			assert.Nil(t, err)
+			resp, err := c.Get(context.Background(), "controller_aaa", "ns_aaa", "/v1/cert.pem"+tc.path)
+			if tc.expectedErr != "" {
+				assert.ErrorContains(t, err, tc.expectedErr)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, "cert_aaa", string(resp))
+		})
+	}
+	assert.Equal(t, "cluster.invalid", proxied)
+
+	_, err := NewClient(&Config{Host: server.URL, ProxyURL: "proxy.example.com"})
+	assert.EqualError(t, err, `invalid proxy URL "proxy.example.com": expected scheme://host[:port]`)
+}
+
+func TestImpersonation(t *testing.T) {
+	var headers http.Header
+	c, err := NewClient(&Config{
+		Host:              "http://127.0.0.1",
+		Token:             "token_aaa",
+		ImpersonateUser:   "system:serviceaccount:kube-system:sealed-secrets-reader",
+		ImpersonateGroups: []string{"group_aaa", "group_bbb"},
+		ImpersonateExtra:  map[string][]string{"scopes": {"scope_aaa", "scope_bbb"}},
+		Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			headers = req.Header.Clone()
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("cert_aaa"))}, nil
+		}),
+	})
+	assert.Nil(t, err)
+	_, err = c.Get(context.Background(), "controller_aaa", "ns_aaa", "/v1/cert.pem")
+	assert.Nil(t, err)
+
+	assert.Equal(t, "system:serviceaccount:kube-system:sealed-secrets-reader", headers.Get("Impersonate-User"))
+	assert.Equal(t, []string{"group_aaa", "group_bbb"}, headers.Values("Impersonate-Group"))
+	assert.Equal(t, []string{"scope_aaa", "scope_bbb"}, headers.Values("Impersonate-Extra-Scopes"))
+	assert.Equal(t, "Bearer token_aaa", headers.Get("Authorization"))
+}
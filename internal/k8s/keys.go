@@ -0,0 +1,42 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// KeyLabel marks the Secrets holding the controller's keys, the key it seals with is labelled active.
+const KeyLabel = "sealedsecrets.bitnami.com/sealed-secrets-key"
+
+// KeyCertLister reads the certificates of the controller's keys from their Secrets.
+type KeyCertLister interface {
+	ActiveKeyCerts(ctx context.Context, namespace string) ([][]byte, error)
+}
+
+// ActiveKeyCerts returns the tls.crt of the active key Secrets in the namespace, ordered by Secret name. It only
+// needs get and list of Secrets instead of access to the controller's service proxy.
+func (c *Client) ActiveKeyCerts(ctx context.Context, namespace string) ([][]byte, error) {
+	selector := KeyLabel + "=active"
+	tflog.SubsystemDebug(ctx, LogSubsystem, "Listing the key Secrets", map[string]interface{}{
+		"namespace":      namespace,
+		"label_selector": selector,
+	})
+	list, err := c.RestClient.Secrets(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list the key Secrets: %w", err)
+	}
+	sort.Slice(list.Items, func(i, j int) bool { return list.Items[i].Name < list.Items[j].Name })
+
+	certs := make([][]byte, 0, len(list.Items))
+	for _, secret := range list.Items {
+		if crt := secret.Data[v1.TLSCertKey]; len(crt) > 0 {
+			certs = append(certs, crt)
+		}
+	}
+	return certs, nil
+}
@@ -0,0 +1,37 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func keySecret(name, namespace, state string, crt string) *v1.Secret {
+	return &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: map[string]string{KeyLabel: state}},
+		Type:       v1.SecretTypeTLS,
+		Data:       map[string][]byte{v1.TLSCertKey: []byte(crt), v1.TLSPrivateKeyKey: []byte("key_" + crt)},
+	}
+}
+
+func TestActiveKeyCerts(t *testing.T) {
+	c := newFakeClient([]runtime.Object{
+		keySecret("sealed-secrets-keyb", "kube-system", "active", "crt_bbb"),
+		keySecret("sealed-secrets-keya", "kube-system", "active", "crt_aaa"),
+		keySecret("sealed-secrets-keyc", "kube-system", "compromised", "crt_ccc"),
+		keySecret("sealed-secrets-keyd", "other", "active", "crt_ddd"),
+		&v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "unlabelled", Namespace: "kube-system"}},
+	})
+
+	certs, err := c.ActiveKeyCerts(context.Background(), "kube-system")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]byte{[]byte("crt_aaa"), []byte("crt_bbb")}, certs)
+
+	certs, err = c.ActiveKeyCerts(context.Background(), "empty")
+	assert.Nil(t, err)
+	assert.Empty(t, certs)
+}
@@ -0,0 +1,35 @@
+package k8s
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// LogSubsystem is the tflog subsystem of the Kubernetes client, TF_LOG_PROVIDER_SEALEDSECRET_K8S sets its level.
+const LogSubsystem = "k8s"
+
+// traceTransport logs every request to the API server at TRACE level. Headers and bodies are never logged, they
+// carry credentials and secret data.
+type traceTransport struct {
+	next http.RoundTripper
+}
+
+func (t traceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	fields := map[string]interface{}{
+		"method":   req.Method,
+		"url":      req.URL.Redacted(),
+		"duration": time.Since(start).String(),
+	}
+	if resp != nil {
+		fields["status"] = resp.StatusCode
+	}
+	if err != nil {
+		fields["error"] = err.Error()
+	}
+	tflog.SubsystemTrace(req.Context(), LogSubsystem, "Kubernetes API request", fields)
+	return resp, err
+}
@@ -1,8 +1,15 @@
 package k8s
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"fmt"
+
 	v1 "k8s.io/api/core/v1"
+	sigsyaml "sigs.k8s.io/yaml"
 )
 
 type SecretManifest struct {
@@ -12,12 +19,18 @@ type SecretManifest struct {
 	Data      map[string]string
 }
 
-var ErrEmptyData = errors.New("secret manifest Data and StringData cannot be empty")
+var (
+	ErrEmptyData      = errors.New("secret manifest Data and StringData cannot be empty")
+	ErrMissingHashKey = errors.New("a key is needed to hash the secret name")
+)
 
 func CreateSecret(sm *SecretManifest) (v1.Secret, error) {
 	if len(sm.Data) == 0 {
 		return v1.Secret{}, ErrEmptyData
 	}
+	if err := ValidateSize(sm.Data); err != nil {
+		return v1.Secret{}, err
+	}
 
 	data := make(map[string][]byte)
 	for key, value := range sm.Data {
@@ -34,3 +47,80 @@ func CreateSecret(sm *SecretManifest) (v1.Secret, error) {
 
 	return secret, nil
 }
+
+// ParseSecretManifest reads the name, namespace, type and data of a Secret in YAML or JSON, the rest of the object is
+// ignored as the sealedsecret resource does not set it either. stringData wins over data, like in the API server.
+func ParseSecretManifest(content []byte) (*SecretManifest, error) {
+	var secret v1.Secret
+	if err := sigsyaml.UnmarshalStrict(content, &secret); err != nil {
+		return nil, fmt.Errorf("unable to parse the secret: %w", err)
+	}
+	if secret.APIVersion != "v1" || secret.Kind != "Secret" {
+		return nil, fmt.Errorf("expected a v1 Secret, got %s %s", secret.APIVersion, secret.Kind)
+	}
+
+	sm := &SecretManifest{
+		Name:      secret.Name,
+		Namespace: secret.Namespace,
+		Type:      string(secret.Type),
+		Data:      make(map[string]string, len(secret.Data)+len(secret.StringData)),
+	}
+	if sm.Type == "" {
+		sm.Type = string(v1.SecretTypeOpaque)
+	}
+	for key, value := range secret.Data {
+		sm.Data[key] = string(value)
+	}
+	for key, value := range secret.StringData {
+		sm.Data[key] = value
+	}
+	return sm, nil
+}
+
+// HashedName appends a hash of the secret's name, type and data to its name, like the Kustomize secretGenerator.
+// The hash is a HMAC-SHA256 keyed with key, so low-entropy values cannot be guessed from the name. The suffix only
+// changes when the name, type or data change.
+func HashedName(sm *SecretManifest, key []byte) (string, error) {
+	if len(key) == 0 {
+		return "", ErrMissingHashKey
+	}
+	if err := ValidateHashedName(sm.Name); err != nil {
+		return "", err
+	}
+	secret, err := CreateSecret(sm)
+	if err != nil {
+		return "", err
+	}
+	encoded, err := json.Marshal(map[string]interface{}{
+		"kind": "Secret",
+		"name": secret.Name,
+		"type": secret.Type,
+		"data": secret.Data,
+	})
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(encoded)
+	return sm.Name + "-" + encodeHash(mac.Sum(nil)), nil
+}
+
+// encodeHash maps the first 10 hex characters to ones that cannot form bad words, as Kustomize does.
+func encodeHash(sum []byte) string {
+	enc := []rune(hex.EncodeToString(sum)[:10])
+	for i := range enc {
+		switch enc[i] {
+		case '0':
+			enc[i] = 'g'
+		case '1':
+			enc[i] = 'h'
+		case '3':
+			enc[i] = 'k'
+		case 'a':
+			enc[i] = 'm'
+		case 'e':
+			enc[i] = 't'
+		}
+	}
+	return string(enc)
+}
@@ -1,8 +1,10 @@
 package k8s
 
 import (
-	"github.com/stretchr/testify/assert"
+	"strings"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestCreateSecret(t *testing.T) {
@@ -15,38 +17,15 @@ func TestCreateSecret(t *testing.T) {
 	}{
 		{
 			Name: "happy day",
-			Input: SecretManifest{
-				Name:       "name_aaa",
-				Namespace:  "ns_aaa",
-				Type:       "type_aaa",
-				Data:       map[string]interface{}{secretKey: secretValue},
-				StringData: map[string]string{secretKey: secretValue},
-			},
-			ExpectedDataValue: secretValue,
-			ExpectedErr:       nil,
-		},
-		{
-			Name: "only data",
 			Input: SecretManifest{
 				Name:      "name_aaa",
 				Namespace: "ns_aaa",
 				Type:      "type_aaa",
-				Data:      map[string]interface{}{secretKey: secretValue},
+				Data:      map[string]string{secretKey: secretValue},
 			},
 			ExpectedDataValue: secretValue,
 			ExpectedErr:       nil,
 		},
-		{
-			Name: "only stringData",
-			Input: SecretManifest{
-				Name:       "name_aaa",
-				Namespace:  "ns_aaa",
-				Type:       "type_aaa",
-				StringData: map[string]string{secretKey: secretValue},
-			},
-			ExpectedDataValue: "",
-			ExpectedErr:       nil,
-		},
 		{
 			Name:        "no data should result in error",
 			Input:       SecretManifest{},
@@ -63,8 +42,86 @@ func TestCreateSecret(t *testing.T) {
 			assert.Equal(t, tc.Input.Namespace, secret.Namespace)
 			assert.Equal(t, tc.Input.Type, string(secret.Type))
 			assert.Equal(t, tc.ExpectedDataValue, string(secret.Data[secretKey]))
-			assert.Equal(t, tc.Input.StringData[secretKey], secret.StringData[secretKey])
 		})
 	}
 
 }
+
+func TestHashedName(t *testing.T) {
+	key := []byte("key_aaa")
+	manifest := SecretManifest{Name: "name-aaa", Namespace: "ns_aaa", Type: "Opaque", Data: map[string]string{"secret": "secret_aaa"}}
+	hashed, err := HashedName(&manifest, key)
+	assert.Nil(t, err)
+	assert.Regexp(t, `^name-aaa-[2456789bcdfghkmt]{10}$`, hashed)
+
+	again, err := HashedName(&SecretManifest{Name: "name-aaa", Namespace: "ns_bbb", Type: "Opaque", Data: map[string]string{"secret": "secret_aaa"}}, key)
+	assert.Nil(t, err)
+	assert.Equal(t, hashed, again, "the namespace is not part of the hash")
+
+	changed, err := HashedName(&SecretManifest{Name: "name-aaa", Namespace: "ns_aaa", Type: "Opaque", Data: map[string]string{"secret": "secret_bbb"}}, key)
+	assert.Nil(t, err)
+	assert.NotEqual(t, hashed, changed)
+
+	rekeyed, err := HashedName(&manifest, []byte("key_bbb"))
+	assert.Nil(t, err)
+	assert.NotEqual(t, hashed, rekeyed, "the suffix is keyed")
+
+	_, err = HashedName(&manifest, nil)
+	assert.Equal(t, ErrMissingHashKey, err)
+
+	_, err = HashedName(&SecretManifest{Name: "name-aaa"}, key)
+	assert.Equal(t, ErrEmptyData, err)
+
+	long, err := HashedName(&SecretManifest{Name: strings.Repeat("a", 242), Data: map[string]string{"secret": "secret_aaa"}}, key)
+	assert.Nil(t, err)
+	assert.Nil(t, ValidateName(long))
+
+	_, err = HashedName(&SecretManifest{Name: strings.Repeat("a", 243), Data: map[string]string{"secret": "secret_aaa"}}, key)
+	assert.ErrorContains(t, err, "too long to append the hash suffix")
+}
+
+func TestParseSecretManifest(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Input    string
+		Expected *SecretManifest
+		Err      bool
+	}{
+		{
+			Name: "yaml",
+			Input: `apiVersion: v1
+kind: Secret
+metadata:
+  name: name-aaa
+  namespace: ns-aaa
+  labels:
+    app: aaa
+data:
+  secret: c2VjcmV0X2FhYQ==
+stringData:
+  other: value_aaa
+`,
+			Expected: &SecretManifest{Name: "name-aaa", Namespace: "ns-aaa", Type: "Opaque", Data: map[string]string{"secret": "secret_aaa", "other": "value_aaa"}},
+		},
+		{
+			Name:     "json, stringData wins",
+			Input:    `{"apiVersion": "v1", "kind": "Secret", "metadata": {"name": "name-aaa"}, "type": "kubernetes.io/tls", "data": {"secret": "c2VjcmV0X2FhYQ=="}, "stringData": {"secret": "value_aaa"}}`,
+			Expected: &SecretManifest{Name: "name-aaa", Type: "kubernetes.io/tls", Data: map[string]string{"secret": "value_aaa"}},
+		},
+		{Name: "not a secret", Input: "apiVersion: v1\nkind: ConfigMap\n", Err: true},
+		{Name: "unknown field", Input: "apiVersion: v1\nkind: Secret\nspec: {}\n", Err: true},
+		{Name: "data not base64", Input: "apiVersion: v1\nkind: Secret\ndata:\n  secret: '%'\n", Err: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			sm, err := ParseSecretManifest([]byte(tc.Input))
+			if tc.Err {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.Expected, sm)
+		})
+	}
+}
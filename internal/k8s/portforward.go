@@ -0,0 +1,162 @@
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	v1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+)
+
+// controllerPort is the name of the controller's service port, the service proxy uses it as well.
+const controllerPort = "http"
+
+// PodDialerFunc opens the SPDY connection of a port-forward to a pod.
+type PodDialerFunc func(namespace, pod string) (httpstream.Dialer, error)
+
+// spdyPodDialer dials the portforward subresource of pods through the API server, like kubectl port-forward.
+func spdyPodDialer(restCfg *rest.Config, c rest.Interface) (PodDialerFunc, error) {
+	transport, upgrader, err := spdy.RoundTripperFor(restCfg)
+	if err != nil {
+		return nil, err
+	}
+	return func(namespace, pod string) (httpstream.Dialer, error) {
+		u := c.Post().Resource("pods").Namespace(namespace).Name(pod).SubResource("portforward").URL()
+		return spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, u), nil
+	}, nil
+}
+
+// forward sends a request to a running pod of the controller's service through a port-forward, for clusters where
+// the service proxy is disabled. The port-forward is closed afterwards.
+func (c *Client) forward(ctx context.Context, controllerName, controllerNamespace, method, path string, body []byte) ([]byte, error) {
+	pod, port, err := c.controllerPod(ctx, controllerName, controllerNamespace)
+	if err != nil {
+		return nil, err
+	}
+	tflog.SubsystemDebug(ctx, LogSubsystem, "Port-forwarding to the controller", map[string]interface{}{
+		"controller":           controllerName,
+		"controller_namespace": controllerNamespace,
+		"pod":                  pod,
+		"port":                 port,
+		"path":                 path,
+	})
+	dialer, err := c.PodDialer(controllerNamespace, pod)
+	if err != nil {
+		return nil, err
+	}
+	stop, ready := make(chan struct{}), make(chan struct{})
+	fw, err := portforward.NewOnAddresses(dialer, []string{"127.0.0.1"}, []string{fmt.Sprintf("0:%d", port)}, stop, ready, io.Discard, io.Discard)
+	if err != nil {
+		return nil, err
+	}
+	done := make(chan error, 1)
+	go func() { done <- fw.ForwardPorts() }()
+	select {
+	case <-ready:
+	case err := <-done:
+		return nil, fmt.Errorf("unable to port-forward to pod %s/%s: %w", controllerNamespace, pod, err)
+	case <-ctx.Done():
+		close(stop)
+		return nil, ctx.Err()
+	}
+	defer func() {
+		close(stop)
+		<-done
+	}()
+
+	ports, err := fw.GetPorts()
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, fmt.Sprintf("http://127.0.0.1:%d%s", ports[0].Local, path), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	resp, err := (&http.Client{Transport: &http.Transport{DisableKeepAlives: true}}).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request to pod %s/%s failed: %w", controllerNamespace, pod, err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read response from pod %s/%s: %w", controllerNamespace, pod, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		// reported like the service proxy does, so callers can check the status code
+		return nil, &k8sErrors.StatusError{ErrStatus: metav1.Status{
+			Status:  metav1.StatusFailure,
+			Code:    int32(resp.StatusCode),
+			Message: fmt.Sprintf("the controller answered %s: %s", resp.Status, bytes.TrimSpace(b)),
+		}}
+	}
+	return b, nil
+}
+
+// controllerPod returns a running pod selected by the controller's service and the container port its http port
+// targets.
+func (c *Client) controllerPod(ctx context.Context, controllerName, controllerNamespace string) (string, int32, error) {
+	svc, err := c.RestClient.Services(controllerNamespace).Get(ctx, controllerName, metav1.GetOptions{})
+	if err != nil {
+		return "", 0, fmt.Errorf("unable to get the controller service: %w", err)
+	}
+	if len(svc.Spec.Selector) == 0 {
+		return "", 0, fmt.Errorf("the controller service %s/%s has no selector", controllerNamespace, controllerName)
+	}
+	var servicePort *v1.ServicePort
+	for i := range svc.Spec.Ports {
+		if svc.Spec.Ports[i].Name == controllerPort {
+			servicePort = &svc.Spec.Ports[i]
+		}
+	}
+	if servicePort == nil {
+		return "", 0, fmt.Errorf("the controller service %s/%s has no port named %s", controllerNamespace, controllerName, controllerPort)
+	}
+
+	pods, err := c.RestClient.Pods(controllerNamespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(svc.Spec.Selector).String(),
+	})
+	if err != nil {
+		return "", 0, fmt.Errorf("unable to list the controller pods: %w", err)
+	}
+	sort.Slice(pods.Items, func(i, j int) bool { return pods.Items[i].Name < pods.Items[j].Name })
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != v1.PodRunning || pod.DeletionTimestamp != nil {
+			continue
+		}
+		if port, ok := targetPort(&pod, servicePort); ok {
+			return pod.Name, port, nil
+		}
+	}
+	return "", 0, fmt.Errorf("no running pod of the controller service %s/%s", controllerNamespace, controllerName)
+}
+
+// targetPort resolves the port of the pod the service port sends traffic to.
+func targetPort(pod *v1.Pod, servicePort *v1.ServicePort) (int32, bool) {
+	switch {
+	case servicePort.TargetPort.Type == intstr.String:
+		for _, container := range pod.Spec.Containers {
+			for _, p := range container.Ports {
+				if p.Name == servicePort.TargetPort.StrVal {
+					return p.ContainerPort, true
+				}
+			}
+		}
+		return 0, false
+	case servicePort.TargetPort.IntVal != 0:
+		return servicePort.TargetPort.IntVal, true
+	default:
+		return servicePort.Port, true
+	}
+}
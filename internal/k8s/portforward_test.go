@@ -0,0 +1,185 @@
+package k8s
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/apimachinery/pkg/util/httpstream/spdy"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/portforward"
+)
+
+type dialerFunc func() (httpstream.Connection, string, error)
+
+func (f dialerFunc) Dial(...string) (httpstream.Connection, string, error) {
+	return f()
+}
+
+// fakePodDialer serves port-forwards like the kubelet does, forwarding the data streams to addr and recording the
+// pods and ports they were opened to.
+type fakePodDialer struct {
+	addr string
+
+	mu    sync.Mutex
+	dials []string
+}
+
+func (d *fakePodDialer) dial(namespace, pod string) (httpstream.Dialer, error) {
+	return dialerFunc(func() (httpstream.Connection, string, error) {
+		client, server := net.Pipe()
+		_, err := spdy.NewServerConnection(server, func(stream httpstream.Stream, replySent <-chan struct{}) error {
+			go func() {
+				<-replySent
+				defer stream.Close()
+				if stream.Headers().Get(v1.StreamType) == v1.StreamTypeError {
+					return
+				}
+				d.mu.Lock()
+				d.dials = append(d.dials, namespace+"/"+pod+":"+stream.Headers().Get(v1.PortHeader))
+				d.mu.Unlock()
+				conn, err := net.Dial("tcp", d.addr)
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+				go func() { _, _ = io.Copy(conn, stream) }()
+				_, _ = io.Copy(stream, conn)
+			}()
+			return nil
+		})
+		if err != nil {
+			return nil, "", err
+		}
+		conn, err := spdy.NewClientConnection(client)
+		return conn, portforward.PortForwardProtocolV1Name, err
+	}), nil
+}
+
+func controllerPodObject(name string, phase v1.PodPhase, labels map[string]string) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "kube-system", Labels: labels},
+		Spec: v1.PodSpec{Containers: []v1.Container{{
+			Name:  "controller",
+			Ports: []v1.ContainerPort{{Name: "http", ContainerPort: 8080}},
+		}}},
+		Status: v1.PodStatus{Phase: phase},
+	}
+}
+
+func TestPortForward(t *testing.T) {
+	controller := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/cert.pem":
+			_, _ = w.Write([]byte("cert"))
+		case "/v1/verify":
+			body, _ := io.ReadAll(r.Body)
+			if string(body) != "valid" {
+				w.WriteHeader(http.StatusConflict)
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer controller.Close()
+
+	selector := map[string]string{"name": "sealed-secrets-controller"}
+	c := newFakeClient([]runtime.Object{
+		&v1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "sealed-secrets-controller", Namespace: "kube-system"},
+			Spec: v1.ServiceSpec{
+				Selector: selector,
+				Ports:    []v1.ServicePort{{Name: "http", Port: 8080, TargetPort: intstr.FromString("http")}},
+			},
+		},
+		&v1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "no-pods", Namespace: "kube-system"},
+			Spec: v1.ServiceSpec{
+				Selector: map[string]string{"name": "other"},
+				Ports:    []v1.ServicePort{{Name: "http", Port: 8080}},
+			},
+		},
+		controllerPodObject("sealed-secrets-controller-a", v1.PodPending, selector),
+		controllerPodObject("sealed-secrets-controller-b", v1.PodRunning, selector),
+		controllerPodObject("other", v1.PodPending, map[string]string{"name": "other"}),
+	})
+
+	dialer := &fakePodDialer{addr: controller.Listener.Addr().String()}
+	c.PodDialer = dialer.dial
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	b, err := c.Get(ctx, "sealed-secrets-controller", "kube-system", "/v1/cert.pem")
+	assert.NoError(t, err)
+	assert.Equal(t, "cert", string(b))
+
+	_, err = c.Post(ctx, "sealed-secrets-controller", "kube-system", "/v1/verify", []byte("valid"))
+	assert.NoError(t, err)
+
+	_, err = c.Post(ctx, "sealed-secrets-controller", "kube-system", "/v1/verify", []byte("invalid"))
+	var status k8sErrors.APIStatus
+	if assert.ErrorAs(t, err, &status) {
+		assert.EqualValues(t, http.StatusConflict, status.Status().Code)
+	}
+
+	dialer.mu.Lock()
+	assert.Equal(t, []string{
+		"kube-system/sealed-secrets-controller-b:8080",
+		"kube-system/sealed-secrets-controller-b:8080",
+		"kube-system/sealed-secrets-controller-b:8080",
+	}, dialer.dials)
+	dialer.mu.Unlock()
+
+	_, err = c.Get(ctx, "no-pods", "kube-system", "/v1/cert.pem")
+	assert.ErrorContains(t, err, "no running pod of the controller service kube-system/no-pods")
+
+	_, err = c.Get(ctx, "missing", "kube-system", "/v1/cert.pem")
+	assert.True(t, k8sErrors.IsNotFound(err))
+}
+
+func TestTargetPort(t *testing.T) {
+	pod := controllerPodObject("pod", v1.PodRunning, nil)
+	tests := []struct {
+		name       string
+		targetPort intstr.IntOrString
+		want       int32
+		wantOk     bool
+	}{
+		{name: "number", targetPort: intstr.FromInt32(9090), want: 9090, wantOk: true},
+		{name: "unset uses the service port", want: 80, wantOk: true},
+		{name: "named container port", targetPort: intstr.FromString("http"), want: 8080, wantOk: true},
+		{name: "unknown container port", targetPort: intstr.FromString("metrics")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := targetPort(pod, &v1.ServicePort{Name: "http", Port: 80, TargetPort: tt.targetPort})
+			assert.Equal(t, tt.wantOk, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNewClientPortForward(t *testing.T) {
+	c, err := NewClient(&Config{Host: "https://127.0.0.1:6443"})
+	assert.NoError(t, err)
+	assert.Nil(t, c.PodDialer)
+
+	c, err = NewClient(&Config{Host: "https://127.0.0.1:6443", PortForward: true})
+	assert.NoError(t, err)
+	if assert.NotNil(t, c.PodDialer) {
+		dialer, err := c.PodDialer("kube-system", "sealed-secrets-controller-b")
+		assert.NoError(t, err)
+		assert.NotNil(t, dialer)
+	}
+}
@@ -0,0 +1,184 @@
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	sigsyaml "sigs.k8s.io/yaml"
+)
+
+const (
+	FieldManager     = "terraform-provider-sealedsecret"
+	sealedSecretKind = "SealedSecret"
+	syncedCondition  = "Synced"
+)
+
+var SealedSecretGVR = schema.GroupVersionResource{Group: "bitnami.com", Version: "v1alpha1", Resource: "sealedsecrets"}
+
+var (
+	ErrNotSealedSecret = errors.New("manifest is not a bitnami.com/v1alpha1 SealedSecret")
+	ErrUnsealFailed    = errors.New("controller failed to unseal")
+)
+
+// SealedSecretClienter manages SealedSecret objects in the cluster.
+type SealedSecretClienter interface {
+	ApplySealedSecret(ctx context.Context, obj *unstructured.Unstructured) (*unstructured.Unstructured, error)
+	GetSealedSecret(ctx context.Context, namespace, name string) (*unstructured.Unstructured, error)
+	DeleteSealedSecret(ctx context.Context, namespace, name string) error
+	WaitForUnseal(ctx context.Context, namespace, name string) error
+}
+
+// DecodeSealedSecret parses a SealedSecret manifest as produced by kubeseal.SealSecret.
+func DecodeSealedSecret(manifest []byte) (*unstructured.Unstructured, error) {
+	obj := &unstructured.Unstructured{}
+	if err := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(manifest), 4096).Decode(&obj.Object); err != nil {
+		return nil, fmt.Errorf("unable to decode manifest: %w", err)
+	}
+	gvk := obj.GroupVersionKind()
+	if gvk.Group != SealedSecretGVR.Group || gvk.Version != SealedSecretGVR.Version || gvk.Kind != sealedSecretKind {
+		return nil, fmt.Errorf("%w, got %s", ErrNotSealedSecret, gvk)
+	}
+	return obj, nil
+}
+
+// ApplySealedSecret creates or updates the SealedSecret with server-side apply.
+func (c *Client) ApplySealedSecret(ctx context.Context, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	applied, err := c.Dynamic.Resource(SealedSecretGVR).Namespace(obj.GetNamespace()).
+		Apply(ctx, obj.GetName(), obj, metav1.ApplyOptions{FieldManager: FieldManager, Force: true})
+	if err != nil {
+		return nil, fmt.Errorf("unable to apply sealed secret %s/%s: %w", obj.GetNamespace(), obj.GetName(), err)
+	}
+	return applied, nil
+}
+
+// GetSealedSecret returns the SealedSecret, the error satisfies k8sErrors.IsNotFound when it does not exist.
+func (c *Client) GetSealedSecret(ctx context.Context, namespace, name string) (*unstructured.Unstructured, error) {
+	return c.Dynamic.Resource(SealedSecretGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+}
+
+// DeleteSealedSecret deletes the SealedSecret, a missing object is not an error.
+func (c *Client) DeleteSealedSecret(ctx context.Context, namespace, name string) error {
+	err := c.Dynamic.Resource(SealedSecretGVR).Namespace(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	if err != nil && !k8sErrors.IsNotFound(err) {
+		return fmt.Errorf("unable to delete sealed secret %s/%s: %w", namespace, name, err)
+	}
+	return nil
+}
+
+// WaitForUnseal polls, with frontoff, until the controller has observed the current generation of the SealedSecret,
+// reports it as Synced and the Secret exists. A Synced condition of False is returned as ErrUnsealFailed.
+func (c *Client) WaitForUnseal(ctx context.Context, namespace, name string) error {
+	var status error
+	attempt := 0
+	err := frontoff.DelayFunc().Until(ctx, true, false, func(ctx context.Context) (bool, error) {
+		attempt++
+		pending := func() (bool, error) {
+			tflog.SubsystemDebug(ctx, LogSubsystem, "Waiting for the controller to unseal", map[string]interface{}{
+				"namespace": namespace,
+				"name":      name,
+				"attempt":   attempt,
+				"status":    status.Error(),
+			})
+			return false, nil
+		}
+		obj, err := c.GetSealedSecret(ctx, namespace, name)
+		if err != nil {
+			return false, err
+		}
+		var done bool
+		if done, status = unsealStatus(obj); !done {
+			return pending()
+		}
+		if status != nil {
+			return true, status
+		}
+		if _, err := c.RestClient.Secrets(namespace).Get(ctx, name, metav1.GetOptions{}); err != nil {
+			if k8sErrors.IsNotFound(err) {
+				status = errors.New("the secret was not created yet")
+				return pending()
+			}
+			return false, err
+		}
+		return true, nil
+	})
+	if errors.Is(err, ErrUnsealFailed) {
+		return err
+	}
+	if err != nil && status != nil {
+		return fmt.Errorf("waiting for sealed secret %s/%s to be unsealed: %w, last status: %s", namespace, name, err, status)
+	}
+	if err != nil {
+		return fmt.Errorf("waiting for sealed secret %s/%s to be unsealed: %w", namespace, name, err)
+	}
+	return nil
+}
+
+// unsealStatus reports whether the controller is done with the current generation, and the failure if there was one.
+// While it is not done the returned error describes what is still pending.
+func unsealStatus(obj *unstructured.Unstructured) (bool, error) {
+	observed, _, _ := unstructured.NestedInt64(obj.Object, "status", "observedGeneration")
+	if observed < obj.GetGeneration() {
+		return false, fmt.Errorf("generation %d was not observed by the controller yet", obj.GetGeneration())
+	}
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if !ok || condition["type"] != syncedCondition {
+			continue
+		}
+		switch condition["status"] {
+		case "True":
+			return true, nil
+		case "False":
+			return true, fmt.Errorf("%w %s/%s: %v (%v)", ErrUnsealFailed, obj.GetNamespace(), obj.GetName(), condition["message"], condition["reason"])
+		}
+	}
+	return false, errors.New("the controller did not report the Synced condition yet")
+}
+
+// SealedSecretSpecEqual reports whether the live object has the desired spec. Null values are ignored as the
+// API server drops them, e.g. the creationTimestamp of the template.
+func SealedSecretSpecEqual(desired, live *unstructured.Unstructured) bool {
+	desiredSpec, _, _ := unstructured.NestedMap(desired.Object, "spec")
+	liveSpec, _, _ := unstructured.NestedMap(live.Object, "spec")
+	return equality.Semantic.DeepEqual(pruneNulls(desiredSpec), pruneNulls(liveSpec))
+}
+
+// EncodeSealedSecret renders the user facing parts of a SealedSecret (without status and server-side metadata) as YAML.
+func EncodeSealedSecret(obj *unstructured.Unstructured) ([]byte, error) {
+	out := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	out.SetAPIVersion(obj.GetAPIVersion())
+	out.SetKind(obj.GetKind())
+	out.SetName(obj.GetName())
+	out.SetNamespace(obj.GetNamespace())
+	out.SetAnnotations(obj.GetAnnotations())
+	out.SetLabels(obj.GetLabels())
+	if spec, ok := obj.Object["spec"]; ok {
+		out.Object["spec"] = spec
+	}
+	return sigsyaml.Marshal(out.Object)
+}
+
+func pruneNulls(m map[string]interface{}) map[string]interface{} {
+	pruned := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		switch value := v.(type) {
+		case nil:
+			continue
+		case map[string]interface{}:
+			pruned[k] = pruneNulls(value)
+		default:
+			pruned[k] = v
+		}
+	}
+	return pruned
+}
@@ -0,0 +1,188 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+const testSealedSecretManifest = `apiVersion: bitnami.com/v1alpha1
+kind: SealedSecret
+metadata:
+  creationTimestamp: null
+  name: name_aaa
+  namespace: ns_aaa
+spec:
+  encryptedData:
+    key: c2VhbGVk
+  template:
+    metadata:
+      creationTimestamp: null
+      name: name_aaa
+      namespace: ns_aaa
+    type: Opaque
+`
+
+// newFakeClient returns a Client backed by a fake dynamic client. The fake does not implement server-side apply
+// of missing objects, so apply patches are turned into creates.
+func newFakeClient(secrets []runtime.Object, objects ...runtime.Object) *Client {
+	scheme := runtime.NewScheme()
+	dyn := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(scheme,
+		map[schema.GroupVersionResource]string{SealedSecretGVR: "SealedSecretList"}, objects...)
+	dyn.PrependReactor("patch", SealedSecretGVR.Resource, func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patch := action.(k8stesting.PatchAction)
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(patch.GetPatch()); err != nil {
+			return true, nil, err
+		}
+		tracker := dyn.Tracker()
+		if _, err := tracker.Get(SealedSecretGVR, patch.GetNamespace(), patch.GetName()); k8sErrors.IsNotFound(err) {
+			return true, obj, tracker.Create(SealedSecretGVR, obj, patch.GetNamespace())
+		}
+		return true, obj, tracker.Update(SealedSecretGVR, obj, patch.GetNamespace())
+	})
+	return &Client{RestClient: kubefake.NewSimpleClientset(secrets...).CoreV1(), Dynamic: dyn}
+}
+
+func withSyncedCondition(t *testing.T, status, message string) *unstructured.Unstructured {
+	obj, err := DecodeSealedSecret([]byte(testSealedSecretManifest))
+	assert.Nil(t, err)
+	obj.SetGeneration(1)
+	assert.Nil(t, unstructured.SetNestedField(obj.Object, int64(1), "status", "observedGeneration"))
+	assert.Nil(t, unstructured.SetNestedSlice(obj.Object, []interface{}{
+		map[string]interface{}{"type": "Synced", "status": status, "message": message, "reason": "ErrUnsealFailed"},
+	}, "status", "conditions"))
+	return obj
+}
+
+func TestDecodeSealedSecret(t *testing.T) {
+	obj, err := DecodeSealedSecret([]byte(testSealedSecretManifest))
+	assert.Nil(t, err)
+	assert.Equal(t, "name_aaa", obj.GetName())
+	assert.Equal(t, "ns_aaa", obj.GetNamespace())
+
+	_, err = DecodeSealedSecret([]byte("apiVersion: v1\nkind: Secret\n"))
+	assert.ErrorIs(t, err, ErrNotSealedSecret)
+}
+
+func TestApplyGetDeleteSealedSecret(t *testing.T) {
+	ctx := context.Background()
+	c := newFakeClient(nil)
+	obj, err := DecodeSealedSecret([]byte(testSealedSecretManifest))
+	assert.Nil(t, err)
+
+	_, err = c.ApplySealedSecret(ctx, obj)
+	assert.Nil(t, err)
+
+	live, err := c.GetSealedSecret(ctx, "ns_aaa", "name_aaa")
+	assert.Nil(t, err)
+	assert.True(t, SealedSecretSpecEqual(obj, live))
+
+	assert.Nil(t, unstructured.SetNestedField(obj.Object, "dXBkYXRlZA==", "spec", "encryptedData", "key"))
+	_, err = c.ApplySealedSecret(ctx, obj)
+	assert.Nil(t, err)
+	updated, err := c.GetSealedSecret(ctx, "ns_aaa", "name_aaa")
+	assert.Nil(t, err)
+	assert.True(t, SealedSecretSpecEqual(obj, updated))
+	assert.False(t, SealedSecretSpecEqual(live, updated))
+
+	assert.Nil(t, c.DeleteSealedSecret(ctx, "ns_aaa", "name_aaa"))
+	_, err = c.GetSealedSecret(ctx, "ns_aaa", "name_aaa")
+	assert.True(t, k8sErrors.IsNotFound(err))
+	assert.Nil(t, c.DeleteSealedSecret(ctx, "ns_aaa", "name_aaa"))
+}
+
+func TestWaitForUnseal(t *testing.T) {
+	secret := &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "name_aaa", Namespace: "ns_aaa"}}
+	newGeneration := withSyncedCondition(t, "True", "")
+	newGeneration.SetGeneration(2)
+
+	tests := []struct {
+		Name          string
+		Object        *unstructured.Unstructured
+		Secrets       []runtime.Object
+		ExpectedErr   string
+		ExpectedIsErr error
+	}{
+		{
+			Name:    "unsealed",
+			Object:  withSyncedCondition(t, "True", ""),
+			Secrets: []runtime.Object{secret},
+		},
+		{
+			Name:          "controller failed to unseal",
+			Object:        withSyncedCondition(t, "False", "no key could decrypt secret (key)"),
+			Secrets:       []runtime.Object{secret},
+			ExpectedErr:   "controller failed to unseal ns_aaa/name_aaa: no key could decrypt secret (key) (ErrUnsealFailed)",
+			ExpectedIsErr: ErrUnsealFailed,
+		},
+		{
+			Name:        "condition of a previous generation",
+			Object:      newGeneration,
+			Secrets:     []runtime.Object{secret},
+			ExpectedErr: "last status: generation 2 was not observed by the controller yet",
+		},
+		{
+			Name:        "secret not created yet",
+			Object:      withSyncedCondition(t, "True", ""),
+			ExpectedErr: "last status: the secret was not created yet",
+		},
+		{
+			Name:        "not synced before the deadline",
+			Object:      withSyncedCondition(t, "Unknown", ""),
+			Secrets:     []runtime.Object{secret},
+			ExpectedErr: "last status: the controller did not report the Synced condition yet",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+
+			err := newFakeClient(tt.Secrets, tt.Object).WaitForUnseal(ctx, "ns_aaa", "name_aaa")
+			if tt.ExpectedErr == "" {
+				assert.Nil(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.ExpectedErr)
+			if tt.ExpectedIsErr != nil {
+				assert.ErrorIs(t, err, tt.ExpectedIsErr)
+			}
+		})
+	}
+}
+
+func TestSealedSecretSpecEqualIgnoresNulls(t *testing.T) {
+	desired, err := DecodeSealedSecret([]byte(testSealedSecretManifest))
+	assert.Nil(t, err)
+	live := desired.DeepCopy()
+	unstructured.RemoveNestedField(live.Object, "spec", "template", "metadata", "creationTimestamp")
+
+	assert.True(t, SealedSecretSpecEqual(desired, live))
+}
+
+func TestEncodeSealedSecret(t *testing.T) {
+	live := withSyncedCondition(t, "True", "")
+	live.SetResourceVersion("42")
+
+	manifest, err := EncodeSealedSecret(live)
+	assert.Nil(t, err)
+	assert.NotContains(t, string(manifest), "status")
+	assert.NotContains(t, string(manifest), "resourceVersion")
+
+	decoded, err := DecodeSealedSecret(manifest)
+	assert.Nil(t, err)
+	assert.True(t, SealedSecretSpecEqual(live, decoded))
+}
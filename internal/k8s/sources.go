@@ -0,0 +1,119 @@
+package k8s
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+
+	sigsyaml "sigs.k8s.io/yaml"
+)
+
+// ParseEnvFile parses KEY=VALUE lines like kubectl create secret --from-env-file. Blank lines and lines
+// starting with # are skipped, a line with only a KEY takes the value from the environment.
+func ParseEnvFile(r io.Reader) (map[string]string, error) {
+	data := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimLeftFunc(scanner.Text(), unicode.IsSpace)
+		if lineNumber == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, hasValue := strings.Cut(line, "=")
+		if !hasValue {
+			value = os.Getenv(key)
+		}
+		if err := ValidateKey(key); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		if _, ok := data[key]; ok {
+			return nil, fmt.Errorf("line %d: duplicate key %q", lineNumber, key)
+		}
+		data[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// ParseJSONData parses a flat JSON object, numbers and booleans are converted to strings.
+func ParseJSONData(content []byte) (map[string]string, error) {
+	dec := json.NewDecoder(bytes.NewReader(content))
+	dec.UseNumber()
+	var raw map[string]interface{}
+	if err := dec.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("unable to decode JSON object: %w", err)
+	}
+	if err := rejectDuplicateJSONKeys(content); err != nil {
+		return nil, err
+	}
+	return flatData(raw)
+}
+
+// ParseYAMLData parses a flat YAML mapping, numbers and booleans are converted to strings.
+func ParseYAMLData(content []byte) (map[string]string, error) {
+	jsonContent, err := sigsyaml.YAMLToJSONStrict(content)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode YAML mapping: %w", err)
+	}
+	return ParseJSONData(jsonContent)
+}
+
+func flatData(raw map[string]interface{}) (map[string]string, error) {
+	keys := make([]string, 0, len(raw))
+	for k := range raw {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	data := make(map[string]string, len(raw))
+	for _, k := range keys {
+		if err := ValidateKey(k); err != nil {
+			return nil, err
+		}
+		switch v := raw[k].(type) {
+		case string:
+			data[k] = v
+		case json.Number, bool:
+			data[k] = fmt.Sprint(v)
+		default:
+			return nil, fmt.Errorf("value of key %q must be a string, number or boolean", k)
+		}
+	}
+	return data, nil
+}
+
+// rejectDuplicateJSONKeys walks the top level object, encoding/json silently keeps the last duplicate.
+func rejectDuplicateJSONKeys(content []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(content))
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	seen := make(map[string]bool)
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key := token.(string)
+		if seen[key] {
+			return fmt.Errorf("duplicate key %q", key)
+		}
+		seen[key] = true
+		var skip json.RawMessage
+		if err := dec.Decode(&skip); err != nil {
+			return err
+		}
+	}
+	return nil
+}
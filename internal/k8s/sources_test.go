@@ -0,0 +1,112 @@
+package k8s
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseEnvFile(t *testing.T) {
+	t.Setenv("FROM_ENV_AAA", "env_aaa")
+
+	tests := []struct {
+		Name        string
+		Input       string
+		Expected    map[string]string
+		ExpectedErr string
+	}{
+		{
+			Name:     "happy day",
+			Input:    "\ufeff# comment\nUSER=user_aaa\n\n  PASSWORD=pass=word\nFROM_ENV_AAA\nEMPTY=\n",
+			Expected: map[string]string{"USER": "user_aaa", "PASSWORD": "pass=word", "FROM_ENV_AAA": "env_aaa", "EMPTY": ""},
+		},
+		{
+			Name:        "duplicate key",
+			Input:       "USER=user_aaa\nUSER=user_bbb\n",
+			ExpectedErr: `line 2: duplicate key "USER"`,
+		},
+		{
+			Name:        "invalid key",
+			Input:       "USER NAME=user_aaa\n",
+			ExpectedErr: `line 1: "USER NAME" is not a valid secret key`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			data, err := ParseEnvFile(strings.NewReader(tc.Input))
+			if tc.ExpectedErr != "" {
+				assert.ErrorContains(t, err, tc.ExpectedErr)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.Expected, data)
+		})
+	}
+}
+
+func TestParseJSONAndYAMLData(t *testing.T) {
+	tests := []struct {
+		Name        string
+		Parse       func([]byte) (map[string]string, error)
+		Input       string
+		Expected    map[string]string
+		ExpectedErr string
+	}{
+		{
+			Name:     "json",
+			Parse:    ParseJSONData,
+			Input:    `{"user": "user_aaa", "port": 5432, "tls": true, "ratio": 0.5}`,
+			Expected: map[string]string{"user": "user_aaa", "port": "5432", "tls": "true", "ratio": "0.5"},
+		},
+		{
+			Name:        "json duplicate key",
+			Parse:       ParseJSONData,
+			Input:       `{"user": "user_aaa", "user": "user_bbb"}`,
+			ExpectedErr: `duplicate key "user"`,
+		},
+		{
+			Name:        "json nested value",
+			Parse:       ParseJSONData,
+			Input:       `{"user": {"name": "user_aaa"}}`,
+			ExpectedErr: `value of key "user" must be a string, number or boolean`,
+		},
+		{
+			Name:        "json invalid key",
+			Parse:       ParseJSONData,
+			Input:       `{"user/name": "user_aaa"}`,
+			ExpectedErr: `"user/name" is not a valid secret key`,
+		},
+		{
+			Name:     "yaml",
+			Parse:    ParseYAMLData,
+			Input:    "user: user_aaa\nport: 5432\n",
+			Expected: map[string]string{"user": "user_aaa", "port": "5432"},
+		},
+		{
+			Name:        "yaml duplicate key",
+			Parse:       ParseYAMLData,
+			Input:       "user: user_aaa\nuser: user_bbb\n",
+			ExpectedErr: `"user" already set in map`,
+		},
+		{
+			Name:        "yaml list",
+			Parse:       ParseYAMLData,
+			Input:       "- user_aaa\n",
+			ExpectedErr: "unable to decode JSON object",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			data, err := tc.Parse([]byte(tc.Input))
+			if tc.ExpectedErr != "" {
+				assert.ErrorContains(t, err, tc.ExpectedErr)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.Expected, data)
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package k8s
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// MaxSecretSize is the limit the API server enforces on the total size of a Secret's data.
+const MaxSecretSize = 1024 * 1024
+
+var ErrSecretTooLarge = fmt.Errorf("secret data must not exceed %d bytes", MaxSecretSize)
+
+// ValidateKey checks a key against the rules for Secret data keys.
+func ValidateKey(key string) error {
+	if errs := validation.IsConfigMapKey(key); len(errs) > 0 {
+		return fmt.Errorf("%q is not a valid secret key: %s", key, strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// ValidateName checks that name is a DNS-1123 subdomain, as required for Secret names.
+func ValidateName(name string) error {
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		return fmt.Errorf("%q is not a valid secret name: %s", name, strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// HashSuffixLength is the length of the suffix HashedName appends to the name.
+const HashSuffixLength = 11
+
+// ValidateHashedName checks that name is a valid Secret name that stays within the DNS-1123 subdomain limit once
+// HashedName appended its suffix.
+func ValidateHashedName(name string) error {
+	if err := ValidateName(name); err != nil {
+		return err
+	}
+	if maxLen := validation.DNS1123SubdomainMaxLength - HashSuffixLength; len(name) > maxLen {
+		return fmt.Errorf("%q is too long to append the hash suffix to: must be no more than %d characters", name, maxLen)
+	}
+	return nil
+}
+
+// ValidateNamespace checks that namespace is a DNS-1123 label, as required for namespace names.
+func ValidateNamespace(namespace string) error {
+	if errs := validation.IsDNS1123Label(namespace); len(errs) > 0 {
+		return fmt.Errorf("%q is not a valid namespace: %s", namespace, strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// ValidateSize checks the total size of the values against MaxSecretSize, as the API server does.
+func ValidateSize(data map[string]string) error {
+	size := 0
+	for _, v := range data {
+		size += len(v)
+	}
+	if size > MaxSecretSize {
+		return fmt.Errorf("%w, got %d bytes", ErrSecretTooLarge, size)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package k8s
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		Name        string
+		Validate    func() error
+		ExpectedErr string
+	}{
+		{Name: "valid name", Validate: func() error { return ValidateName("name-aaa.example") }},
+		{Name: "uppercase name", Validate: func() error { return ValidateName("Name") }, ExpectedErr: `"Name" is not a valid secret name`},
+		{Name: "underscore in name", Validate: func() error { return ValidateName("name_aaa") }, ExpectedErr: `"name_aaa" is not a valid secret name`},
+		{Name: "hashed name at the limit", Validate: func() error { return ValidateHashedName(strings.Repeat("a", 242)) }},
+		{Name: "hashed name over the limit", Validate: func() error { return ValidateHashedName(strings.Repeat("a", 243)) },
+			ExpectedErr: "is too long to append the hash suffix to: must be no more than 242 characters"},
+		{Name: "valid namespace", Validate: func() error { return ValidateNamespace("ns-aaa") }},
+		{Name: "dot in namespace", Validate: func() error { return ValidateNamespace("ns.aaa") }, ExpectedErr: `"ns.aaa" is not a valid namespace`},
+		{Name: "valid key", Validate: func() error { return ValidateKey("tls.crt") }},
+		{Name: "slash in key", Validate: func() error { return ValidateKey("a/b") }, ExpectedErr: `"a/b" is not a valid secret key`},
+		{Name: "size at the limit", Validate: func() error {
+			return ValidateSize(map[string]string{"a": strings.Repeat("x", MaxSecretSize-1), "b": "x"})
+		}},
+		{Name: "size over the limit", Validate: func() error {
+			return ValidateSize(map[string]string{"a": strings.Repeat("x", MaxSecretSize), "b": "x"})
+		}, ExpectedErr: "secret data must not exceed 1048576 bytes, got 1048577 bytes"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			err := tc.Validate()
+			if tc.ExpectedErr != "" {
+				assert.ErrorContains(t, err, tc.ExpectedErr)
+				return
+			}
+			assert.Nil(t, err)
+		})
+	}
+}
+
+func TestCreateSecretTooLarge(t *testing.T) {
+	_, err := CreateSecret(&SecretManifest{Name: "name-aaa", Data: map[string]string{"a": strings.Repeat("x", MaxSecretSize+1)}})
+	assert.ErrorIs(t, err, ErrSecretTooLarge)
+}
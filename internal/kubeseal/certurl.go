@@ -0,0 +1,207 @@
+package kubeseal
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"k8s.io/client-go/rest"
+)
+
+// CertURL is where to fetch the certificates from instead of the controller, like kubeseal --cert.
+type CertURL struct {
+	// URL is an https:// URL, a file:// URL or a local path.
+	URL string
+	// AllowHTTP accepts http:// URLs, whose certificates anyone on the network path can replace with their own.
+	AllowHTTP bool
+	// CACert is the PEM bundle of the CAs to trust instead of the system ones. It and the client certificate are
+	// only accepted for https:// URLs.
+	CACert []byte
+	// ClientCert and ClientKey are the PEM encoded TLS client certificate and key, if the server asks for one.
+	ClientCert []byte
+	ClientKey  []byte
+	// CacheDir keeps downloaded certificates with their ETag and Last-Modified, so the server can answer that they
+	// did not change. Nothing is cached when it is empty.
+	CacheDir string
+}
+
+var (
+	// ErrPlainHTTP is returned for http:// certificate URLs unless CertURL.AllowHTTP is set.
+	ErrPlainHTTP = errors.New("the certificate URL is plain http, anyone on the network path could replace the certificate and read the secrets sealed with it")
+	// ErrTLSWithoutHTTPS is returned when CACert, ClientCert or ClientKey are set for a URL they would not be used for.
+	ErrTLSWithoutHTTPS = errors.New("the CA and client certificates are only used for https:// certificate URLs")
+)
+
+// cachedCerts is the cache file of a certificate URL.
+type cachedCerts struct {
+	// URL is the certificate URL without its credentials and query, the file name is derived from it.
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+	PEM          []byte `json:"pem"`
+}
+
+// FetchCertsFromURL fetches the certificates from src.URL. Like FetchCerts the result is cached once a request
+// succeeds.
+func FetchCertsFromURL(src CertURL) (CertsResolverFunc, error) {
+	u, err := url.Parse(src.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid certificate URL: %w", err)
+	}
+	hasTLS := len(src.CACert) > 0 || len(src.ClientCert) > 0 || len(src.ClientKey) > 0
+	display := src.URL
+	var fetch func(ctx context.Context) ([]byte, error)
+	switch u.Scheme {
+	case "http":
+		if !src.AllowHTTP {
+			return nil, ErrPlainHTTP
+		}
+		if hasTLS {
+			return nil, ErrTLSWithoutHTTPS
+		}
+		display = cacheURL(u)
+		client := &http.Client{Timeout: 30 * time.Second}
+		fetch = func(ctx context.Context) ([]byte, error) {
+			return download(ctx, client, u, src.CacheDir)
+		}
+	case "https":
+		rt, err := rest.TransportFor(&rest.Config{TLSClientConfig: rest.TLSClientConfig{
+			CAData:   src.CACert,
+			CertData: src.ClientCert,
+			KeyData:  src.ClientKey,
+		}})
+		if err != nil {
+			return nil, fmt.Errorf("invalid TLS configuration of the certificate URL: %w", err)
+		}
+		client := &http.Client{Transport: rt, Timeout: 30 * time.Second}
+		display = cacheURL(u)
+		fetch = func(ctx context.Context) ([]byte, error) {
+			return download(ctx, client, u, src.CacheDir)
+		}
+	case "file", "":
+		if hasTLS {
+			return nil, ErrTLSWithoutHTTPS
+		}
+		file := src.URL
+		if u.Scheme == "file" {
+			file = u.Path
+		}
+		fetch = func(context.Context) ([]byte, error) { return os.ReadFile(file) }
+	default:
+		return nil, fmt.Errorf("unsupported certificate URL scheme %q", u.Scheme)
+	}
+
+	var mu sync.Mutex
+	var certs []*x509.Certificate
+	return func(ctx context.Context) ([]*x509.Certificate, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if certs != nil {
+			return certs, nil
+		}
+
+		tflog.SubsystemDebug(ctx, LogSubsystem, "Fetching the certificates", map[string]interface{}{"certificate_url": display})
+		data, err := fetch(ctx)
+		if err != nil {
+			return nil, err
+		}
+		parsed, err := ParseCerts(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", display, err)
+		}
+		certs = parsed
+		return certs, nil
+	}, nil
+}
+
+// cacheURL returns u without the user info, query and fragment, which may hold credentials. It names the cache
+// file and is used in logs and errors.
+func cacheURL(u *url.URL) string {
+	stripped := *u
+	stripped.User = nil
+	stripped.RawQuery = ""
+	stripped.ForceQuery = false
+	stripped.Fragment = ""
+	stripped.RawFragment = ""
+	return stripped.String()
+}
+
+// download GETs the URL. With a cacheDir the cached copy is revalidated with its ETag and Last-Modified instead of
+// being downloaded again.
+func download(ctx context.Context, client *http.Client, u *url.URL, cacheDir string) ([]byte, error) {
+	display := cacheURL(u)
+	var cached cachedCerts
+	var cacheFile string
+	if cacheDir != "" {
+		sum := sha256.Sum256([]byte(display))
+		cacheFile = filepath.Join(cacheDir, hex.EncodeToString(sum[:])+".json")
+		if b, err := os.ReadFile(cacheFile); err == nil && (json.Unmarshal(b, &cached) != nil || cached.URL != display) {
+			cached = cachedCerts{}
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(cached.PEM) > 0 {
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		// the url.Error repeats the URL with its query
+		var uerr *url.Error
+		if errors.As(err, &uerr) {
+			err = uerr.Err
+		}
+		return nil, fmt.Errorf("unable to fetch the certificate from %s: %w", display, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && len(cached.PEM) > 0:
+		tflog.SubsystemDebug(ctx, LogSubsystem, "The certificates did not change", map[string]interface{}{"certificate_url": display})
+		return cached.PEM, nil
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("unable to fetch the certificate from %s: %s", display, resp.Status)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if cacheFile != "" && (resp.Header.Get("ETag") != "" || resp.Header.Get("Last-Modified") != "") {
+		writeCache(ctx, cacheFile, cachedCerts{URL: display, ETag: resp.Header.Get("ETag"), LastModified: resp.Header.Get("Last-Modified"), PEM: body})
+	}
+	return body, nil
+}
+
+// writeCache stores the certificates, failing to do so only costs a download next time.
+func writeCache(ctx context.Context, file string, c cachedCerts) {
+	b, err := json.Marshal(c)
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(file), 0o700)
+	}
+	if err == nil {
+		err = os.WriteFile(file, b, 0o600)
+	}
+	if err != nil {
+		tflog.SubsystemDebug(ctx, LogSubsystem, "Unable to cache the certificates", map[string]interface{}{"error": err.Error()})
+	}
+}
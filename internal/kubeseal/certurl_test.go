@@ -0,0 +1,176 @@
+package kubeseal
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	pemEncoding "encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newClientCert returns a self-signed TLS client certificate and its key, PEM encoded.
+func newClientCert(t *testing.T) (*x509.Certificate, []byte, []byte) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "client"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.Nil(t, err)
+	c, err := x509.ParseCertificate(der)
+	assert.Nil(t, err)
+	keyPEM := pemEncoding.EncodeToMemory(&pemEncoding.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	return c, certPEM(c), keyPEM
+}
+
+func TestFetchCertsFromURL(t *testing.T) {
+	sealingCert := newCert(t, time.Now())
+	clientCert, clientCertPEM, clientKeyPEM := newClientCert(t)
+
+	var downloads, revalidations int
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			revalidations++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		downloads++
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write(certPEM(sealingCert))
+	}))
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	server.StartTLS()
+	defer server.Close()
+
+	src := CertURL{
+		URL:        server.URL + "/cert.pem",
+		CACert:     certPEM(server.Certificate()),
+		ClientCert: clientCertPEM,
+		ClientKey:  clientKeyPEM,
+		CacheDir:   t.TempDir(),
+	}
+	for i := 0; i < 2; i++ {
+		certs, err := FetchCertsFromURL(src)
+		assert.Nil(t, err)
+		fetched, err := certs(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, []*x509.Certificate{sealingCert}, fetched)
+		// cached in memory
+		_, err = certs(context.Background())
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, 1, downloads)
+	assert.Equal(t, 1, revalidations)
+
+	withoutClientCert := src
+	withoutClientCert.ClientCert, withoutClientCert.ClientKey = nil, nil
+	certs, err := FetchCertsFromURL(withoutClientCert)
+	assert.Nil(t, err)
+	_, err = certs(context.Background())
+	assert.NotNil(t, err)
+
+	untrusted := src
+	untrusted.CACert = nil
+	certs, err = FetchCertsFromURL(untrusted)
+	assert.Nil(t, err)
+	_, err = certs(context.Background())
+	assert.ErrorContains(t, err, "certificate")
+
+	_, err = FetchCertsFromURL(CertURL{URL: "ftp://example.com/cert.pem"})
+	assert.EqualError(t, err, `unsupported certificate URL scheme "ftp"`)
+
+	// the TLS settings are only used for https
+	for _, u := range []string{"http://example.com/cert.pem", "file:///cert.pem", "/cert.pem"} {
+		_, err = FetchCertsFromURL(CertURL{URL: u, AllowHTTP: true, CACert: src.CACert})
+		assert.ErrorIs(t, err, ErrTLSWithoutHTTPS, u)
+	}
+}
+
+func TestFetchCertsFromURLCache(t *testing.T) {
+	sealingCert := newCert(t, time.Now())
+	var downloads int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		downloads++
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write(certPEM(sealingCert))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	for _, token := range []string{"token_aaa", "token_bbb"} {
+		u := strings.Replace(server.URL, "http://", "http://user:"+token+"@", 1) + "/cert.pem?token=" + token
+		certs, err := FetchCertsFromURL(CertURL{URL: u, AllowHTTP: true, CacheDir: dir})
+		assert.Nil(t, err)
+		_, err = certs(context.Background())
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, 1, downloads, "the cache is shared by the URLs that only differ in their credentials")
+
+	files, err := os.ReadDir(dir)
+	assert.Nil(t, err)
+	if assert.Len(t, files, 1) {
+		b, err := os.ReadFile(filepath.Join(dir, files[0].Name()))
+		assert.Nil(t, err)
+		assert.NotContains(t, files[0].Name()+string(b), "token_")
+		var cached cachedCerts
+		assert.Nil(t, json.Unmarshal(b, &cached))
+		assert.Equal(t, server.URL+"/cert.pem", cached.URL)
+	}
+}
+
+func TestFetchCertsFromHTTPURL(t *testing.T) {
+	sealingCert := newCert(t, time.Now())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(certPEM(sealingCert))
+	}))
+	defer server.Close()
+
+	_, err := FetchCertsFromURL(CertURL{URL: server.URL + "/cert.pem"})
+	assert.ErrorIs(t, err, ErrPlainHTTP)
+
+	certs, err := FetchCertsFromURL(CertURL{URL: server.URL + "/cert.pem", AllowHTTP: true})
+	assert.Nil(t, err)
+	fetched, err := certs(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, []*x509.Certificate{sealingCert}, fetched)
+}
+
+func TestFetchCertsFromFile(t *testing.T) {
+	sealingCert := newCert(t, time.Now())
+	file := filepath.Join(t.TempDir(), "cert.pem")
+	assert.Nil(t, os.WriteFile(file, certPEM(sealingCert), 0o600))
+
+	for _, u := range []string{file, "file://" + file} {
+		certs, err := FetchCertsFromURL(CertURL{URL: u})
+		assert.Nil(t, err)
+		fetched, err := certs(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, []*x509.Certificate{sealingCert}, fetched)
+	}
+}
@@ -0,0 +1,40 @@
+package kubeseal
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+// deterministicEncrypter seals values with randomness derived from the public key, the label and the
+// value itself. Terraform requires provider functions to return the same result for the same
+// arguments, which a random session key cannot satisfy.
+//
+// The session key is only ever used for the value it was derived from, so the zero nonce used by
+// HybridEncrypt stays safe. The price is that equal plaintexts produce equal ciphertexts: anyone
+// holding the certificate can confirm a guessed value, so it should not be used for low-entropy secrets.
+func deterministicEncrypter(pk *rsa.PublicKey) encrypter {
+	return func(label, value []byte) (string, error) {
+		h := sha256.New()
+		for _, b := range [][]byte{pk.N.Bytes(), binary.BigEndian.AppendUint64(nil, uint64(pk.E)), label, value} {
+			h.Write(binary.BigEndian.AppendUint64(nil, uint64(len(b))))
+			h.Write(b)
+		}
+
+		block, err := aes.NewCipher(h.Sum(nil))
+		if err != nil {
+			return "", err
+		}
+		stream := cipher.NewCTR(block, make([]byte, aes.BlockSize))
+		return encrypt(cipher.StreamReader{S: stream, R: zeroReader{}}, pk, label, value)
+	}
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
@@ -2,54 +2,327 @@ package kubeseal
 
 import (
 	"context"
+	"crypto/rand"
 	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	ssv1alpha1 "github.com/bitnami-labs/sealed-secrets/pkg/apis/sealedsecrets/v1alpha1"
+	"github.com/bitnami-labs/sealed-secrets/pkg/crypto"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
 	"github.com/jifwin/terraform-provider-sealedsecret/internal/k8s"
+	"io"
 	v1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	runtimeserializer "k8s.io/apimachinery/pkg/runtime/serializer"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/util/cert"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
 )
 
+// The controller the provider and the CLI talk to when none is configured.
+const (
+	DefaultControllerName      = "sealed-data-controller"
+	DefaultControllerNamespace = "kube-system"
+)
+
+// LogSubsystem is the tflog subsystem of sealing, TF_LOG_PROVIDER_SEALEDSECRET_KUBESEAL sets its level.
+const LogSubsystem = "kubeseal"
+
+// PKResolverFunc returns the key to seal with. When its certificate expired, or expires soon, the key is returned
+// together with an *ExpiryError unless the certificate is rejected.
 type PKResolverFunc = func(ctx context.Context) (*rsa.PublicKey, error)
 
-func FetchPK(c k8s.Clienter, controllerName, controllerNamespace string) PKResolverFunc {
-	doReq := func(ctx context.Context) (*rsa.PublicKey, error) {
+// CertsResolverFunc returns every certificate the controller serves.
+type CertsResolverFunc = func(ctx context.Context) ([]*x509.Certificate, error)
+
+var ErrCertNotFound = errors.New("the controller serves no certificate with the fingerprint")
+
+// FetchCerts fetches the certificates of all keys the controller serves at /v1/cert.pem. The result is cached
+// once a request succeeds.
+func FetchCerts(c k8s.Clienter, controllerName, controllerNamespace string) CertsResolverFunc {
+	var mu sync.Mutex
+	var certs []*x509.Certificate
+
+	return func(ctx context.Context) ([]*x509.Certificate, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if certs != nil {
+			return certs, nil
+		}
+
+		fields := map[string]interface{}{"controller": controllerName, "controller_namespace": controllerNamespace}
+		tflog.SubsystemDebug(ctx, LogSubsystem, "Fetching the certificates", fields)
 		resp, err := c.Get(ctx, controllerName, controllerNamespace, "/v1/cert.pem")
 		if err != nil {
 			return nil, err
 		}
-		certs, err := cert.ParseCertsPEM(resp)
+		parsed, err := ParseCerts(resp)
 		if err != nil {
 			return nil, err
 		}
+		fields["certificate_fingerprints"] = fingerprints(parsed)
+		tflog.SubsystemDebug(ctx, LogSubsystem, "Fetched the certificates", fields)
+		certs = parsed
+		return certs, nil
+	}
+}
+
+// FetchCertsFromSecrets reads the certificates of the active keys from the controller's key Secrets, for clusters
+// where its HTTP service is disabled. They are ordered oldest first, like the controller serves them, and cached
+// once a request succeeds.
+func FetchCertsFromSecrets(c k8s.KeyCertLister, controllerNamespace string) CertsResolverFunc {
+	var mu sync.Mutex
+	var certs []*x509.Certificate
 
-		pk, ok := certs[0].PublicKey.(*rsa.PublicKey)
-		if !ok {
-			err = fmt.Errorf("expected public key, got: %v", certs[0].PublicKey)
+	return func(ctx context.Context) ([]*x509.Certificate, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if certs != nil {
+			return certs, nil
 		}
-		return pk, nil
+
+		crts, err := c.ActiveKeyCerts(ctx, controllerNamespace)
+		if err != nil {
+			return nil, err
+		}
+		if len(crts) == 0 {
+			return nil, fmt.Errorf("no Secret in namespace %s is labelled %s=active", controllerNamespace, k8s.KeyLabel)
+		}
+		var parsed []*x509.Certificate
+		for _, crt := range crts {
+			p, err := ParseCerts(crt)
+			if err != nil {
+				return nil, err
+			}
+			parsed = append(parsed, p...)
+		}
+		sort.SliceStable(parsed, func(i, j int) bool { return parsed[i].NotBefore.Before(parsed[j].NotBefore) })
+		tflog.SubsystemDebug(ctx, LogSubsystem, "Read the certificates from the key Secrets", map[string]interface{}{
+			"controller_namespace":     controllerNamespace,
+			"certificate_fingerprints": fingerprints(parsed),
+		})
+		certs = parsed
+		return certs, nil
 	}
+}
 
-	var publicKey *rsa.PublicKey
-	var err error
+// ErrCannotUnseal is returned by Verify when the controller cannot unseal a SealedSecret with any of its keys.
+var ErrCannotUnseal = errors.New("the controller cannot unseal the sealed secret")
 
+// Verify asks the controller whether it can unseal the SealedSecret manifest, like kubeseal --validate.
+func Verify(ctx context.Context, c k8s.Poster, controllerName, controllerNamespace string, manifest []byte) error {
+	if _, err := c.Post(ctx, controllerName, controllerNamespace, "/v1/verify", manifest); err != nil {
+		// the controller answers 409 Conflict, which client-go reports as AlreadyExists for a POST
+		var status k8sErrors.APIStatus
+		if errors.As(err, &status) && status.Status().Code == http.StatusConflict {
+			return ErrCannotUnseal
+		}
+		return err
+	}
+	return nil
+}
+
+// FetchPK resolves the public key of the newest certificate the controller serves.
+func FetchPK(c k8s.Clienter, controllerName, controllerNamespace string) PKResolverFunc {
+	return SelectPK(FetchCerts(c, controllerName, controllerNamespace), "", ValidityPolicy{})
+}
+
+// SelectPK resolves the public key of the certificate with the fingerprint pin, or of the newest one when pin is empty.
+// The certificate is checked against the policy.
+func SelectPK(certs CertsResolverFunc, pin string, policy ValidityPolicy) PKResolverFunc {
 	return func(ctx context.Context) (*rsa.PublicKey, error) {
-		//TODO: refactor
-		if err != nil { //&& k8sErrors.IsNotFound(err) || k8sErrors.IsServiceUnavailable(err) {
-			publicKey, err = doReq(ctx)
+		all, err := certs(ctx)
+		if err != nil {
+			return nil, err
+		}
+		selected, err := SelectCert(all, pin)
+		if err != nil {
+			return nil, err
+		}
+		pk := selected.PublicKey.(*rsa.PublicKey)
+		tflog.SubsystemDebug(ctx, LogSubsystem, "Selected the certificate", map[string]interface{}{
+			"certificate_fingerprint": Fingerprint(pk),
+			"pinned":                  pin != "",
+		})
+		if expiry := policy.Check(selected, time.Now()); expiry != nil {
+			tflog.SubsystemWarn(ctx, LogSubsystem, expiry.Error())
+			if expiry.Expired && policy.RejectExpired {
+				return nil, expiry
+			}
+			return pk, expiry
+		}
+		return pk, nil
+	}
+}
+
+// ValidityPolicy decides which certificates SelectPK reports because of their NotAfter.
+type ValidityPolicy struct {
+	// MinValidity reports certificates expiring within this duration, expired ones are always reported.
+	MinValidity time.Duration
+	// RejectExpired makes SelectPK fail for expired certificates instead of returning their key.
+	RejectExpired bool
+}
+
+// ExpiryError reports a certificate that expired or expires within the minimum validity.
+type ExpiryError struct {
+	Subject     string
+	Fingerprint string
+	NotAfter    time.Time
+	Expired     bool
+}
+
+func (e *ExpiryError) Error() string {
+	verb := "expires"
+	if e.Expired {
+		verb = "expired"
+	}
+	subject := ""
+	if e.Subject != "" {
+		subject = " " + e.Subject
+	}
+	return fmt.Sprintf("controller certificate%s (%s) %s on %s", subject, e.Fingerprint, verb, e.NotAfter.UTC().Format(time.RFC3339))
+}
+
+// Check returns an *ExpiryError when the certificate expired or expires within MinValidity of now.
+func (p ValidityPolicy) Check(c *x509.Certificate, now time.Time) *ExpiryError {
+	if !c.NotAfter.Before(now.Add(p.MinValidity)) {
+		return nil
+	}
+	return &ExpiryError{
+		Subject:     c.Subject.String(),
+		Fingerprint: Fingerprint(c.PublicKey.(*rsa.PublicKey)),
+		NotAfter:    c.NotAfter,
+		Expired:     now.After(c.NotAfter),
+	}
+}
+
+// SelectCert returns the certificate with the fingerprint pin, or the newest by NotBefore when pin is empty.
+func SelectCert(certs []*x509.Certificate, pin string) (*x509.Certificate, error) {
+	if pin != "" {
+		for _, c := range certs {
+			if Fingerprint(c.PublicKey.(*rsa.PublicKey)) == pin {
+				return c, nil
+			}
+		}
+		return nil, fmt.Errorf("%w %s", ErrCertNotFound, pin)
+	}
+	newest := certs[0]
+	for _, c := range certs[1:] {
+		if !c.NotBefore.Before(newest.NotBefore) {
+			newest = c
 		}
-		if publicKey == nil && err == nil {
-			publicKey, err = doReq(ctx)
+	}
+	return newest, nil
+}
+
+func fingerprints(certs []*x509.Certificate) []string {
+	fprs := make([]string, 0, len(certs))
+	for _, c := range certs {
+		fprs = append(fprs, Fingerprint(c.PublicKey.(*rsa.PublicKey)))
+	}
+	return fprs
+}
+
+// ParseCerts returns all certificates in the PEM encoded data, they must hold RSA public keys.
+func ParseCerts(data []byte) ([]*x509.Certificate, error) {
+	certs, err := cert.ParseCertsPEM(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range certs {
+		if _, ok := c.PublicKey.(*rsa.PublicKey); !ok {
+			return nil, fmt.Errorf("expected RSA public key, got: %T", c.PublicKey)
 		}
-		return publicKey, err
 	}
+	return certs, nil
+}
+
+// ParsePK returns the public key of the newest certificate in the PEM encoded data.
+func ParsePK(data []byte) (*rsa.PublicKey, error) {
+	certs, err := ParseCerts(data)
+	if err != nil {
+		return nil, err
+	}
+	newest, err := SelectCert(certs, "")
+	if err != nil {
+		return nil, err
+	}
+	return newest.PublicKey.(*rsa.PublicKey), nil
+}
+
+// Fingerprint returns the SHA256 fingerprint of the key as printed by the controller, e.g. SHA256:abc...
+func Fingerprint(pk *rsa.PublicKey) string {
+	fingerprint, err := crypto.PublicKeyFingerprint(pk)
+	if err != nil {
+		// only fails for keys ssh does not support, which ParseCerts rejects
+		return ""
+	}
+	return fingerprint
+}
+
+// ParseScope converts a kubeseal --scope value (strict, namespace-wide or cluster-wide) into a SealingScope.
+func ParseScope(scope string) (ssv1alpha1.SealingScope, error) {
+	var s ssv1alpha1.SealingScope
+	if err := s.Set(scope); err != nil {
+		return s, fmt.Errorf("invalid scope %q: %w", scope, err)
+	}
+	return s, nil
+}
+
+// SetScope annotates the secret so that SealSecret seals it with the given scope.
+func SetScope(secret *v1.Secret, scope ssv1alpha1.SealingScope) {
+	secret.Annotations = ssv1alpha1.UpdateScopeAnnotations(secret.Annotations, scope)
+	if len(secret.Annotations) == 0 {
+		secret.Annotations = nil
+	}
+}
+
+// SealValue encrypts a single value the same way kubeseal --raw does and returns it base64 encoded.
+func SealValue(pk *rsa.PublicKey, namespace, name string, scope ssv1alpha1.SealingScope, value []byte) (string, error) {
+	return randomEncrypter(pk)(ssv1alpha1.EncryptionLabel(namespace, name, scope), value)
+}
+
+// SealValueDeterministic is SealValue, but sealing the same value with the same key, label and scope
+// always produces the same ciphertext.
+func SealValueDeterministic(pk *rsa.PublicKey, namespace, name string, scope ssv1alpha1.SealingScope, value []byte) (string, error) {
+	return deterministicEncrypter(pk)(ssv1alpha1.EncryptionLabel(namespace, name, scope), value)
 }
 
 func SealSecret(secret v1.Secret, pk *rsa.PublicKey) ([]byte, error) {
+	return sealSecret(secret, pk, randomEncrypter(pk))
+}
+
+// SealSecretDeterministic is SealSecret, but the same secret sealed with the same key always produces
+// the same manifest. See deterministicEncrypter for the trade-offs.
+func SealSecretDeterministic(secret v1.Secret, pk *rsa.PublicKey) ([]byte, error) {
+	return sealSecret(secret, pk, deterministicEncrypter(pk))
+}
+
+// encrypter returns the base64 encoded ciphertext of value for the given label.
+type encrypter func(label, value []byte) (string, error)
+
+func randomEncrypter(pk *rsa.PublicKey) encrypter {
+	return func(label, value []byte) (string, error) {
+		return encrypt(rand.Reader, pk, label, value)
+	}
+}
+
+func encrypt(rnd io.Reader, pk *rsa.PublicKey, label, value []byte) (string, error) {
+	out, err := crypto.HybridEncrypt(rnd, pk, value, label)
+	if err != nil {
+		return "", fmt.Errorf("unable to seal value: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(out), nil
+}
+
+func sealSecret(secret v1.Secret, pk *rsa.PublicKey, enc encrypter) ([]byte, error) {
 	codecs := scheme.Codecs
 
 	// Strip read-only server-side ObjectMeta (if present)
@@ -61,11 +334,30 @@ func SealSecret(secret v1.Secret, pk *rsa.PublicKey) ([]byte, error) {
 	secret.SetDeletionTimestamp(nil)
 	secret.DeletionGracePeriodSeconds = nil
 
+	// the values are encrypted below so the encrypter decides where the randomness comes from
+	data := make(map[string][]byte, len(secret.Data)+len(secret.StringData))
+	for key, value := range secret.Data {
+		data[key] = value
+	}
+	for key, value := range secret.StringData {
+		data[key] = []byte(value)
+	}
+	secret.Data = nil
+	secret.StringData = nil
+
 	sealedSecret, err := ssv1alpha1.NewSealedSecret(codecs, pk, &secret)
 	if err != nil {
 		return nil, fmt.Errorf("unable to seal secret: %w", err)
 	}
 
+	label := ssv1alpha1.EncryptionLabel(secret.Namespace, secret.Name, ssv1alpha1.SecretScope(&secret))
+	for key, value := range data {
+		sealedSecret.Spec.EncryptedData[key], err = enc(label, value)
+		if err != nil {
+			return nil, fmt.Errorf("unable to seal secret: %w", err)
+		}
+	}
+
 	prettyEnc, err := prettyEncoder(codecs, runtime.ContentTypeYAML, ssv1alpha1.SchemeGroupVersion)
 	if err != nil {
 		return nil, err
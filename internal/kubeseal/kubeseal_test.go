@@ -1,15 +1,27 @@
 package kubeseal
 
 import (
+	"bytes"
 	"context"
+	"crypto/rand"
 	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	pemEncoding "encoding/pem"
+	ssv1alpha1 "github.com/bitnami-labs/sealed-secrets/pkg/apis/sealedsecrets/v1alpha1"
+	"github.com/bitnami-labs/sealed-secrets/pkg/crypto"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-log/tflogtest"
 	"github.com/jifwin/terraform-provider-sealedsecret/internal/k8s"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/yaml"
+	"math/big"
 	"testing"
+	"time"
 )
 
 const pem = `-----BEGIN CERTIFICATE-----
@@ -62,12 +74,41 @@ func TestFetchPK(t *testing.T) {
 	assert.Equal(t, 65537, pk.E)
 }
 
+type keyCertListerMock struct {
+	mock.Mock
+}
+
+func (m *keyCertListerMock) ActiveKeyCerts(ctx context.Context, namespace string) ([][]byte, error) {
+	args := m.Called(ctx, namespace)
+	return args.Get(0).([][]byte), args.Error(1)
+}
+
+func TestFetchCertsFromSecrets(t *testing.T) {
+	now := time.Now()
+	older, newer := newCert(t, now.Add(-time.Hour)), newCert(t, now)
+
+	m := keyCertListerMock{}
+	m.On("ActiveKeyCerts", context.Background(), "ns").Return([][]byte{certPEM(newer), certPEM(older)}, nil).Once()
+	certs := FetchCertsFromSecrets(&m, "ns")
+	fetched, err := certs(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, []*x509.Certificate{older, newer}, fetched)
+	// cached
+	_, err = certs(context.Background())
+	assert.Nil(t, err)
+	m.AssertExpectations(t)
+
+	m.On("ActiveKeyCerts", context.Background(), "empty").Return([][]byte{}, nil)
+	_, err = FetchCertsFromSecrets(&m, "empty")(context.Background())
+	assert.EqualError(t, err, "no Secret in namespace empty is labelled sealedsecrets.bitnami.com/sealed-secrets-key=active")
+}
+
 func TestSealSecret(t *testing.T) {
 	sm := k8s.SecretManifest{
-		Name:       "name_aa",
-		Namespace:  "ns_aa",
-		Type:       "type_aa",
-		StringData: map[string]string{"keyAA": "valueAA"},
+		Name:      "name_aa",
+		Namespace: "ns_aa",
+		Type:      "type_aa",
+		Data:      map[string]string{"keyAA": "valueAA"},
 	}
 
 	m := K8sClientMock{}
@@ -181,3 +222,294 @@ func TestRequestIsRetriedOnRetryableError(t *testing.T) {
 		})
 	}
 }
+
+func TestParsePK(t *testing.T) {
+	pk, err := ParsePK([]byte(pem))
+	assert.Nil(t, err)
+	assert.Equal(t, 65537, pk.E)
+
+	_, err = ParsePK([]byte("not a certificate"))
+	assert.NotNil(t, err)
+}
+
+func TestSealValue(t *testing.T) {
+	tests := []struct {
+		Name          string
+		Scope         string
+		ExpectedLabel string
+		ExpectedErr   string
+	}{
+		{
+			Name:          "strict is the default",
+			Scope:         "",
+			ExpectedLabel: "ns_aa/name_aa",
+		},
+		{
+			Name:          "namespace-wide",
+			Scope:         "namespace-wide",
+			ExpectedLabel: "ns_aa",
+		},
+		{
+			Name:          "cluster-wide",
+			Scope:         "cluster-wide",
+			ExpectedLabel: "",
+		},
+		{
+			Name:        "unknown scope",
+			Scope:       "galaxy-wide",
+			ExpectedErr: "invalid scope \"galaxy-wide\": must be one of: strict, namespace-wide, cluster-wide",
+		},
+	}
+
+	key, _, err := crypto.GeneratePrivateKeyAndCert(2048, time.Hour, "test")
+	assert.Nil(t, err)
+
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			scope, err := ParseScope(tc.Scope)
+			if tc.ExpectedErr != "" {
+				assert.EqualError(t, err, tc.ExpectedErr)
+				return
+			}
+			assert.Nil(t, err)
+
+			sealed, err := SealValue(&key.PublicKey, "ns_aa", "name_aa", scope, []byte("valueAA"))
+			assert.Nil(t, err)
+
+			ciphertext, err := base64.StdEncoding.DecodeString(sealed)
+			assert.Nil(t, err)
+			plaintext, err := crypto.HybridDecrypt(rand.Reader, map[string]*rsa.PrivateKey{"": key}, ciphertext, []byte(tc.ExpectedLabel))
+			assert.Nil(t, err)
+			assert.Equal(t, "valueAA", string(plaintext))
+		})
+	}
+}
+
+func TestSetScope(t *testing.T) {
+	secret, err := k8s.CreateSecret(&k8s.SecretManifest{Name: "name_aa", Namespace: "ns_aa", Data: map[string]string{"keyAA": "valueAA"}})
+	assert.Nil(t, err)
+
+	SetScope(&secret, ssv1alpha1.NamespaceWideScope)
+	assert.Equal(t, ssv1alpha1.NamespaceWideScope, ssv1alpha1.SecretScope(&secret))
+
+	SetScope(&secret, ssv1alpha1.StrictScope)
+	assert.Nil(t, secret.Annotations)
+}
+
+func TestSealDeterministic(t *testing.T) {
+	key, _, err := crypto.GeneratePrivateKeyAndCert(2048, time.Hour, "test")
+	assert.Nil(t, err)
+	pk := &key.PublicKey
+
+	first, err := SealValueDeterministic(pk, "ns_aa", "name_aa", ssv1alpha1.StrictScope, []byte("valueAA"))
+	assert.Nil(t, err)
+	second, err := SealValueDeterministic(pk, "ns_aa", "name_aa", ssv1alpha1.StrictScope, []byte("valueAA"))
+	assert.Nil(t, err)
+	assert.Equal(t, first, second)
+
+	other, err := SealValueDeterministic(pk, "ns_aa", "other_aa", ssv1alpha1.StrictScope, []byte("valueAA"))
+	assert.Nil(t, err)
+	assert.NotEqual(t, first, other)
+
+	ciphertext, err := base64.StdEncoding.DecodeString(first)
+	assert.Nil(t, err)
+	plaintext, err := crypto.HybridDecrypt(rand.Reader, map[string]*rsa.PrivateKey{"": key}, ciphertext, []byte("ns_aa/name_aa"))
+	assert.Nil(t, err)
+	assert.Equal(t, "valueAA", string(plaintext))
+
+	secret, err := k8s.CreateSecret(&k8s.SecretManifest{Name: "name_aa", Namespace: "ns_aa", Data: map[string]string{"a": "1", "b": "2"}})
+	assert.Nil(t, err)
+	firstManifest, err := SealSecretDeterministic(secret, pk)
+	assert.Nil(t, err)
+	secondManifest, err := SealSecretDeterministic(secret, pk)
+	assert.Nil(t, err)
+	assert.Equal(t, string(firstManifest), string(secondManifest))
+}
+
+func newCert(t *testing.T, notBefore time.Time) *x509.Certificate {
+	return newCertValidUntil(t, notBefore, notBefore.Add(24*time.Hour))
+}
+
+func newCertValidUntil(t *testing.T, notBefore, notAfter time.Time) *x509.Certificate {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(notBefore.UnixNano()),
+		Subject:      pkix.Name{CommonName: "sealed-secret"},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.Nil(t, err)
+	c, err := x509.ParseCertificate(der)
+	assert.Nil(t, err)
+	return c
+}
+
+func TestSelectCert(t *testing.T) {
+	now := time.Now()
+	older, newer, sameAsNewer := newCert(t, now.Add(-time.Hour)), newCert(t, now), newCert(t, now)
+	fpr := func(c *x509.Certificate) string { return Fingerprint(c.PublicKey.(*rsa.PublicKey)) }
+
+	tests := []struct {
+		name     string
+		certs    []*x509.Certificate
+		pin      string
+		expected *x509.Certificate
+		err      error
+	}{
+		{name: "single", certs: []*x509.Certificate{older}, expected: older},
+		{name: "newest", certs: []*x509.Certificate{older, newer}, expected: newer},
+		{name: "newest regardless of order", certs: []*x509.Certificate{newer, older}, expected: newer},
+		{name: "later wins ties", certs: []*x509.Certificate{older, newer, sameAsNewer}, expected: sameAsNewer},
+		{name: "pinned", certs: []*x509.Certificate{older, newer}, pin: fpr(older), expected: older},
+		{name: "pin not served", certs: []*x509.Certificate{newer}, pin: fpr(older), err: ErrCertNotFound},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			selected, err := SelectCert(tc.certs, tc.pin)
+			assert.ErrorIs(t, err, tc.err)
+			assert.Equal(t, tc.expected, selected)
+		})
+	}
+}
+
+func TestParseCerts(t *testing.T) {
+	older, newer := newCert(t, time.Now().Add(-time.Hour)), newCert(t, time.Now())
+	data := append(certPEM(older), certPEM(newer)...)
+
+	certs, err := ParseCerts(data)
+	assert.Nil(t, err)
+	assert.Equal(t, []*x509.Certificate{older, newer}, certs)
+
+	pk, err := ParsePK(append(certPEM(newer), certPEM(older)...))
+	assert.Nil(t, err)
+	assert.Equal(t, newer.PublicKey, pk)
+}
+
+func TestSelectPK(t *testing.T) {
+	older, newer := newCert(t, time.Now().Add(-time.Hour)), newCert(t, time.Now())
+	m := K8sClientMock{}
+	m.On(getFunc, context.Background(), "name", "ns", "/v1/cert.pem").Return(string(append(certPEM(older), certPEM(newer)...)), nil)
+	certs := FetchCerts(&m, "name", "ns")
+
+	pk, err := SelectPK(certs, "", ValidityPolicy{})(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, newer.PublicKey, pk)
+
+	// the pinned certificate is logged, not the newest one
+	var out bytes.Buffer
+	ctx := tflog.NewSubsystem(tflogtest.RootLogger(context.Background(), &out), LogSubsystem)
+	pk, err = SelectPK(certs, Fingerprint(older.PublicKey.(*rsa.PublicKey)), ValidityPolicy{})(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, older.PublicKey, pk)
+	m.AssertNumberOfCalls(t, getFunc, 1)
+
+	entries, err := tflogtest.MultilineJSONDecode(&out)
+	assert.Nil(t, err)
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, Fingerprint(older.PublicKey.(*rsa.PublicKey)), entries[0]["certificate_fingerprint"])
+		assert.Equal(t, true, entries[0]["pinned"])
+	}
+}
+
+func TestValidityPolicy(t *testing.T) {
+	now := time.Now()
+	c := newCertValidUntil(t, now.Add(-time.Hour), now.Add(48*time.Hour))
+	expired := newCertValidUntil(t, now.Add(-2*time.Hour), now.Add(-time.Hour))
+
+	tests := []struct {
+		name     string
+		cert     *x509.Certificate
+		policy   ValidityPolicy
+		reported bool
+		expired  bool
+	}{
+		{name: "valid", cert: c},
+		{name: "valid longer than the minimum", cert: c, policy: ValidityPolicy{MinValidity: 24 * time.Hour}},
+		{name: "expires within the minimum", cert: c, policy: ValidityPolicy{MinValidity: 72 * time.Hour}, reported: true},
+		{name: "expired", cert: expired, reported: true, expired: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			expiry := tc.policy.Check(tc.cert, now)
+			if !tc.reported {
+				assert.Nil(t, expiry)
+				return
+			}
+			assert.Equal(t, tc.expired, expiry.Expired)
+			assert.Equal(t, "CN=sealed-secret", expiry.Subject)
+			assert.Equal(t, Fingerprint(tc.cert.PublicKey.(*rsa.PublicKey)), expiry.Fingerprint)
+			assert.Contains(t, expiry.Error(), tc.cert.NotAfter.UTC().Format(time.RFC3339))
+			assert.Contains(t, expiry.Error(), expiry.Fingerprint)
+		})
+	}
+}
+
+func TestSelectPKExpiry(t *testing.T) {
+	now := time.Now()
+	expired := newCertValidUntil(t, now.Add(-2*time.Hour), now.Add(-time.Hour))
+	certs := func(context.Context) ([]*x509.Certificate, error) { return []*x509.Certificate{expired}, nil }
+	var expiry *ExpiryError
+
+	pk, err := SelectPK(certs, "", ValidityPolicy{})(context.Background())
+	assert.Equal(t, expired.PublicKey, pk, "the key is still returned")
+	assert.ErrorAs(t, err, &expiry)
+	assert.True(t, expiry.Expired)
+
+	pk, err = SelectPK(certs, "", ValidityPolicy{RejectExpired: true})(context.Background())
+	assert.Nil(t, pk)
+	assert.ErrorAs(t, err, &expiry)
+}
+
+func certPEM(c *x509.Certificate) []byte {
+	return pemEncoding.EncodeToMemory(&pemEncoding.Block{Type: "CERTIFICATE", Bytes: c.Raw})
+}
+
+func TestMergeSealedSecret(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	secret, err := k8s.CreateSecret(&k8s.SecretManifest{
+		Name:      "name-aaa",
+		Namespace: "ns-aaa",
+		Type:      "Opaque",
+		Data:      map[string]string{"kept": "kept_aaa", "replaced": "old_aaa", "removed": "removed_aaa"},
+	})
+	assert.Nil(t, err)
+	SetScope(&secret, ssv1alpha1.NamespaceWideScope)
+	manifest, err := SealSecret(secret, &key.PublicKey)
+	assert.Nil(t, err)
+	original, err := ParseSealedSecret(manifest)
+	assert.Nil(t, err)
+
+	merged, err := MergeSealedSecret(manifest, &key.PublicKey, map[string]string{"replaced": "new_aaa", "added": "added_aaa"}, []string{"removed"})
+	assert.Nil(t, err)
+	sealedSecret, err := ParseSealedSecret(merged)
+	assert.Nil(t, err)
+
+	assert.Equal(t, original.ObjectMeta, sealedSecret.ObjectMeta)
+	assert.Equal(t, original.Spec.EncryptedData["kept"], sealedSecret.Spec.EncryptedData["kept"])
+	assert.NotContains(t, sealedSecret.Spec.EncryptedData, "removed")
+	label := ssv1alpha1.EncryptionLabel("ns-aaa", "name-aaa", ssv1alpha1.NamespaceWideScope)
+	for k, expected := range map[string]string{"kept": "kept_aaa", "replaced": "new_aaa", "added": "added_aaa"} {
+		ciphertext, err := base64.StdEncoding.DecodeString(sealedSecret.Spec.EncryptedData[k])
+		assert.Nil(t, err)
+		plaintext, err := crypto.HybridDecrypt(rand.Reader, map[string]*rsa.PrivateKey{"": key}, ciphertext, label)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, string(plaintext))
+	}
+
+	_, err = MergeSealedSecret(merged, &key.PublicKey, nil, []string{"kept", "replaced", "added"})
+	assert.ErrorIs(t, err, ErrNoKeysLeft)
+	_, err = MergeSealedSecret([]byte("apiVersion: v1\nkind: Secret\n"), &key.PublicKey, nil, nil)
+	assert.NotNil(t, err)
+
+	// the ciphertext of keys sealed without a namespace could never be decrypted
+	withoutNamespace := bytes.Replace(merged, []byte("namespace: ns-aaa"), nil, 1)
+	_, err = MergeSealedSecret(withoutNamespace, &key.PublicKey, map[string]string{"added": "added_aaa"}, nil)
+	assert.ErrorIs(t, err, ErrNoNamespace)
+	_, err = MergeSealedSecret(withoutNamespace, &key.PublicKey, nil, []string{"added"})
+	assert.Nil(t, err, "keys can still be removed")
+}
@@ -0,0 +1,64 @@
+package kubeseal
+
+import (
+	"crypto/rsa"
+	"errors"
+	"fmt"
+
+	ssv1alpha1 "github.com/bitnami-labs/sealed-secrets/pkg/apis/sealedsecrets/v1alpha1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+var (
+	ErrNoKeysLeft  = errors.New("the sealed secret has no keys left")
+	ErrNoNamespace = errors.New("the sealed secret has no metadata.namespace, keys can only be added to it with the cluster-wide scope")
+)
+
+// ParseSealedSecret parses a SealedSecret manifest in YAML or JSON.
+func ParseSealedSecret(manifest []byte) (*ssv1alpha1.SealedSecret, error) {
+	obj, err := runtime.Decode(scheme.Codecs.UniversalDecoder(ssv1alpha1.SchemeGroupVersion), manifest)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse the sealed secret: %w", err)
+	}
+	sealedSecret, ok := obj.(*ssv1alpha1.SealedSecret)
+	if !ok {
+		return nil, fmt.Errorf("expected a SealedSecret, got %T", obj)
+	}
+	return sealedSecret, nil
+}
+
+// MergeSealedSecret seals data for the name, namespace and scope of the SealedSecret manifest, like
+// kubeseal --merge-into, and drops the remove keys. The other keys are kept as they are, no plaintext is needed.
+func MergeSealedSecret(manifest []byte, pk *rsa.PublicKey, data map[string]string, remove []string) ([]byte, error) {
+	sealedSecret, err := ParseSealedSecret(manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	scope := ssv1alpha1.SecretScope(sealedSecret)
+	if len(data) > 0 && sealedSecret.Namespace == "" && scope != ssv1alpha1.ClusterWideScope {
+		return nil, ErrNoNamespace
+	}
+	if sealedSecret.Spec.EncryptedData == nil {
+		sealedSecret.Spec.EncryptedData = make(map[string]string, len(data))
+	}
+	for _, key := range remove {
+		delete(sealedSecret.Spec.EncryptedData, key)
+	}
+	for key, value := range data {
+		sealedSecret.Spec.EncryptedData[key], err = SealValue(pk, sealedSecret.Namespace, sealedSecret.Name, scope, []byte(value))
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(sealedSecret.Spec.EncryptedData) == 0 {
+		return nil, ErrNoKeysLeft
+	}
+
+	prettyEnc, err := prettyEncoder(scheme.Codecs, runtime.ContentTypeYAML, ssv1alpha1.SchemeGroupVersion)
+	if err != nil {
+		return nil, err
+	}
+	return runtime.Encode(prettyEnc, sealedSecret)
+}
@@ -0,0 +1,446 @@
+package provider
+
+import (
+	"context"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	ssv1alpha1 "github.com/bitnami-labs/sealed-secrets/pkg/apis/sealedsecrets/v1alpha1"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	fwprovider "github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	fwresource "github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/plancheck"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+	"github.com/jifwin/terraform-provider-sealedsecret/internal/acctest"
+	"github.com/jifwin/terraform-provider-sealedsecret/internal/k8s"
+)
+
+// The acceptance tests run Terraform against an in-process fake cluster, they need TF_ACC=1 and a terraform
+// binary on the PATH or in TF_ACC_TERRAFORM_PATH.
+
+var testAccProviders = map[string]func() (tfprotov5.ProviderServer, error){
+	"sealedsecret": providerserver.NewProtocol5WithError(New("test")()),
+}
+
+// sdkProviders serves sealedsecret with the schema of the terraform-plugin-sdk implementation, to create the state
+// the upgrade starts from.
+var sdkProviders = map[string]func() (tfprotov5.ProviderServer, error){
+	"sealedsecret": providerserver.NewProtocol5WithError(&sdkProvider{Provider: New("test")()}),
+}
+
+// sdkProvider is the current provider serving only the sdkSealedSecretResource.
+type sdkProvider struct {
+	fwprovider.Provider
+}
+
+func (p *sdkProvider) Resources(context.Context) []func() fwresource.Resource {
+	return []func() fwresource.Resource{func() fwresource.Resource { return &sdkSealedSecretResource{} }}
+}
+
+func (p *sdkProvider) DataSources(context.Context) []func() datasource.DataSource {
+	return nil
+}
+
+// sdkSealedSecretResource stores what the terraform-plugin-sdk implementation did: the name as id and no attributes
+// added since.
+type sdkSealedSecretResource struct {
+	provider *ProviderConfig
+}
+
+func (r *sdkSealedSecretResource) Metadata(ctx context.Context, req fwresource.MetadataRequest, resp *fwresource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName
+}
+
+func (r *sdkSealedSecretResource) Schema(ctx context.Context, req fwresource.SchemaRequest, resp *fwresource.SchemaResponse) {
+	resp.Schema = *(&sealedSecretResource{}).UpgradeState(ctx)[0].PriorSchema
+}
+
+func (r *sdkSealedSecretResource) Configure(ctx context.Context, req fwresource.ConfigureRequest, resp *fwresource.ConfigureResponse) {
+	if req.ProviderData != nil {
+		r.provider = req.ProviderData.(*ProviderConfig)
+	}
+}
+
+func (r *sdkSealedSecretResource) Create(ctx context.Context, req fwresource.CreateRequest, resp *fwresource.CreateResponse) {
+	var plan sealedSecretModelV0
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	manifest := k8s.SecretManifest{Name: plan.Name.ValueString(), Namespace: plan.Namespace.ValueString(), Type: stringOrDefault(plan.Type, defaultType)}
+	resp.Diagnostics.Append(plan.Data.ElementsAs(ctx, &manifest.Data, false)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	sealed, pk, err := createSealedSecret(ctx, r.provider, &manifest, ssv1alpha1.StrictScope)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to seal", err.Error())
+		return
+	}
+	plan.ID = plan.Name
+	plan.DataHmac = types.MapNull(types.StringType)
+	plan.YamlContent = types.StringValue(string(sealed))
+	plan.PublicKey = types.StringValue(formatPublicKeyAsString(pk))
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *sdkSealedSecretResource) Read(ctx context.Context, req fwresource.ReadRequest, resp *fwresource.ReadResponse) {
+}
+
+func (r *sdkSealedSecretResource) Update(ctx context.Context, req fwresource.UpdateRequest, resp *fwresource.UpdateResponse) {
+	resp.State.Raw = req.Plan.Raw
+}
+
+func (r *sdkSealedSecretResource) Delete(ctx context.Context, req fwresource.DeleteRequest, resp *fwresource.DeleteResponse) {
+}
+
+const testAccSealedSecretConfig = `
+resource "sealedsecret" "db" {
+  name      = "db"
+  namespace = "default"
+  data      = { password = "secret_aaa" }
+}
+`
+
+// checkUnsealsTo checks that the controller unseals yaml_content into the given value of the password key.
+func checkUnsealsTo(cluster *acctest.Cluster, password string) resource.CheckResourceAttrWithFunc {
+	return func(manifest string) error {
+		if err := cluster.Verify([]byte(manifest)); err != nil {
+			return err
+		}
+		secret, err := cluster.Unseal([]byte(manifest))
+		if err != nil {
+			return err
+		}
+		if got := string(secret.Data["password"]); got != password {
+			return fmt.Errorf("expected password %q, got %q", password, got)
+		}
+		return nil
+	}
+}
+
+func TestAccSealedSecret(t *testing.T) {
+	cluster := acctest.NewCluster(t)
+	var sealed string
+
+	resource.Test(t, resource.TestCase{
+		Steps: []resource.TestStep{
+			{
+				// created by the terraform-plugin-sdk implementation
+				ProtoV5ProviderFactories: sdkProviders,
+				Config:                   cluster.ProviderConfig() + testAccSealedSecretConfig,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("sealedsecret.db", "id", "db"),
+					resource.TestCheckResourceAttrWith("sealedsecret.db", "yaml_content", func(v string) error {
+						sealed = v
+						return nil
+					}),
+				),
+			},
+			{
+				// the state is upgraded without sealing the secret again
+				ProtoV5ProviderFactories: testAccProviders,
+				Config:                   cluster.ProviderConfig() + testAccSealedSecretConfig,
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{plancheck.ExpectResourceAction("sealedsecret.db", plancheck.ResourceActionNoop)},
+				},
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("sealedsecret.db", "id", "default/db"),
+					resource.TestCheckResourceAttr("sealedsecret.db", "secret_name", "db"),
+					resource.TestCheckResourceAttrWith("sealedsecret.db", "yaml_content", checkUnsealsTo(cluster, "secret_aaa")),
+					resource.TestCheckResourceAttrWith("sealedsecret.db", "yaml_content", func(v string) error {
+						if v != sealed {
+							return fmt.Errorf("expected the upgraded secret to be kept")
+						}
+						return nil
+					}),
+				),
+			},
+			{
+				// imported from the SealedSecret applied to the cluster, the plaintext cannot be read back and the manifest
+				// is encoded from the cluster's copy
+				PreConfig:                func() { cluster.ApplySealedSecret(t, []byte(sealed)) },
+				ProtoV5ProviderFactories: testAccProviders,
+				Config:                   cluster.ProviderConfig() + testAccSealedSecretConfig,
+				ResourceName:             "sealedsecret.db",
+				ImportState:              true,
+				ImportStateId:            "default/db",
+				ImportStateVerify:        true,
+				ImportStateVerifyIgnore:  []string{"data", "yaml_content"},
+			},
+			{
+				// the controller rotated its key, the secret is sealed again with the new one
+				PreConfig:                func() { cluster.RotateKey(t) },
+				ProtoV5ProviderFactories: testAccProviders,
+				Config:                   cluster.ProviderConfig() + testAccSealedSecretConfig,
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{plancheck.ExpectResourceAction("sealedsecret.db", plancheck.ResourceActionReplace)},
+				},
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrWith("sealedsecret.db", "yaml_content", checkUnsealsTo(cluster, "secret_aaa")),
+					resource.TestCheckResourceAttrWith("sealedsecret.db", "yaml_content", func(v string) error {
+						if v == sealed || !cluster.SealedWithLatestKey([]byte(v)) {
+							return fmt.Errorf("expected the secret to be sealed with the latest key")
+						}
+						return nil
+					}),
+					resource.TestCheckResourceAttr("sealedsecret.db", "needs_reseal", "false"),
+					resource.TestCheckResourceAttrWith("sealedsecret.db", "sealed_with_fingerprint", func(v string) error {
+						sealed = v
+						return nil
+					}),
+				),
+			},
+			{
+				// without resealing the secret is kept and only marked
+				PreConfig:                func() { cluster.RotateKey(t) },
+				ProtoV5ProviderFactories: testAccProviders,
+				Config:                   cluster.ProviderConfig("reseal_on_key_rotation = false") + testAccSealedSecretConfig,
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{plancheck.ExpectResourceAction("sealedsecret.db", plancheck.ResourceActionUpdate)},
+				},
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("sealedsecret.db", "needs_reseal", "true"),
+					resource.TestCheckResourceAttrWith("sealedsecret.db", "sealed_with_fingerprint", func(v string) error {
+						if v != sealed {
+							return fmt.Errorf("expected the fingerprint %s to be kept, got %s", sealed, v)
+						}
+						return nil
+					}),
+				),
+			},
+		},
+	})
+}
+
+func TestAccSealedSecretMerge(t *testing.T) {
+	cluster := acctest.NewCluster(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV5ProviderFactories: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: cluster.ProviderConfig("multiple_controllers = true") + testAccSealedSecretConfig + `
+resource "sealedsecret_merge" "db" {
+  yaml_content = sealedsecret.db.yaml_content
+  data         = { user = "user_aaa" }
+}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("sealedsecret_merge.db", "id", "default/db@"+acctest.ControllerNamespace+"/"+acctest.ControllerName),
+					resource.TestCheckResourceAttrWith("sealedsecret_merge.db", "merged_yaml_content", checkUnsealsTo(cluster, "secret_aaa")),
+					resource.TestCheckResourceAttrWith("sealedsecret_merge.db", "merged_yaml_content", func(v string) error {
+						secret, err := cluster.Unseal([]byte(v))
+						if err != nil {
+							return err
+						}
+						if got := string(secret.Data["user"]); got != "user_aaa" {
+							return fmt.Errorf("expected user %q, got %q", "user_aaa", got)
+						}
+						return nil
+					}),
+				),
+			},
+			{
+				Config: cluster.ProviderConfig("multiple_controllers = true", `hmac_key = "key_aaa"`) + testAccSealedSecretConfig + `
+resource "sealedsecret_merge" "db" {
+  yaml_content = sealedsecret.db.yaml_content
+  data_wo      = { user = "user_bbb" }
+  hash_data    = true
+}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckNoResourceAttr("sealedsecret_merge.db", "data_wo"),
+					resource.TestCheckResourceAttr("sealedsecret_merge.db", "data_hmac.%", "1"),
+					resource.TestCheckResourceAttrWith("sealedsecret_merge.db", "merged_yaml_content", func(v string) error {
+						secret, err := cluster.Unseal([]byte(v))
+						if err != nil {
+							return err
+						}
+						if got := string(secret.Data["user"]); got != "user_bbb" {
+							return fmt.Errorf("expected user %q, got %q", "user_bbb", got)
+						}
+						return nil
+					}),
+				),
+			},
+			{
+				// the controller rotated its key, the new keys are merged again
+				PreConfig: func() { cluster.RotateKey(t) },
+				Config: cluster.ProviderConfig("multiple_controllers = true", `hmac_key = "key_aaa"`) + testAccSealedSecretConfig + `
+resource "sealedsecret_merge" "db" {
+  yaml_content = sealedsecret.db.yaml_content
+  data_wo      = { user = "user_bbb" }
+  hash_data    = true
+}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("sealedsecret_merge.db", "needs_reseal", "false"),
+					resource.TestCheckResourceAttrWith("sealedsecret_merge.db", "merged_yaml_content", checkUnsealsTo(cluster, "secret_aaa")),
+				),
+			},
+			{
+				Config: cluster.ProviderConfig() + testAccSealedSecretConfig + `
+resource "sealedsecret_merge" "db" {
+  yaml_content = sealedsecret.db.yaml_content
+  remove_keys  = ["password"]
+}
+`,
+				ExpectError: regexp.MustCompile("no keys left"),
+			},
+		},
+	})
+}
+
+func TestAccSealedSecretInCluster(t *testing.T) {
+	cluster := acctest.NewCluster(t)
+	config := cluster.ProviderConfig() + testAccSealedSecretConfig + `
+resource "sealedsecret_in_cluster" "db" {
+  yaml_content    = sealedsecret.db.yaml_content
+  wait_for_synced = true
+}
+`
+	checkSecret := func(*terraform.State) error {
+		secret, ok := cluster.Secret("default", "db")
+		if !ok {
+			return fmt.Errorf("the controller did not create the secret")
+		}
+		if got := string(secret.Data["password"]); got != "secret_aaa" {
+			return fmt.Errorf("expected password %q, got %q", "secret_aaa", got)
+		}
+		return nil
+	}
+
+	resource.Test(t, resource.TestCase{
+		ProtoV5ProviderFactories: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("sealedsecret_in_cluster.db", "id", "default/db"),
+					resource.TestCheckResourceAttr("sealedsecret_in_cluster.db", "name", "db"),
+					resource.TestCheckResourceAttr("sealedsecret_in_cluster.db", "namespace", "default"),
+					checkSecret,
+				),
+			},
+			{
+				ResourceName:      "sealedsecret_in_cluster.db",
+				ImportState:       true,
+				ImportStateId:     "default/db",
+				ImportStateVerify: true,
+				// imported objects are read back from the cluster, waiting is a setting of the configuration
+				ImportStateVerifyIgnore: []string{"yaml_content", "wait_for_synced"},
+			},
+			{
+				// deleted behind Terraform's back, Read notices and the object is applied again
+				PreConfig: func() { cluster.DeleteSealedSecret("default", "db") },
+				Config:    config,
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{plancheck.ExpectResourceAction("sealedsecret_in_cluster.db", plancheck.ResourceActionCreate)},
+				},
+				Check: checkSecret,
+			},
+		},
+	})
+}
+
+func TestAccCertificates(t *testing.T) {
+	cluster := acctest.NewCluster(t)
+	cluster.RotateKey(t)
+	fingerprints := cluster.Fingerprints()
+	const config = `
+data "sealedsecret_certificates" "all" {}
+`
+
+	resource.Test(t, resource.TestCase{
+		ProtoV5ProviderFactories: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: cluster.ProviderConfig() + config,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.sealedsecret_certificates.all", "certificates.#", "2"),
+					resource.TestCheckResourceAttr("data.sealedsecret_certificates.all", "certificates.0.fingerprint", fingerprints[0]),
+					resource.TestCheckResourceAttr("data.sealedsecret_certificates.all", "certificates.0.current", "false"),
+					resource.TestCheckResourceAttr("data.sealedsecret_certificates.all", "certificates.1.fingerprint", fingerprints[1]),
+					resource.TestCheckResourceAttr("data.sealedsecret_certificates.all", "certificates.1.current", "true"),
+					resource.TestCheckResourceAttrSet("data.sealedsecret_certificates.all", "certificates.1.pem"),
+					resource.TestCheckResourceAttr("data.sealedsecret_certificates.all", "current_fingerprint", fingerprints[1]),
+				),
+			},
+			{
+				// pinned to the older key, which the controller still unseals with
+				Config: cluster.ProviderConfig(fmt.Sprintf("certificate_fingerprint = %q", fingerprints[0])) + config + testAccSealedSecretConfig,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.sealedsecret_certificates.all", "current_fingerprint", fingerprints[0]),
+					resource.TestCheckResourceAttr("data.sealedsecret_certificates.all", "certificates.0.current", "true"),
+					resource.TestCheckResourceAttr("sealedsecret.db", "sealed_with_fingerprint", fingerprints[0]),
+					resource.TestCheckResourceAttr("sealedsecret.db", "needs_reseal", "false"),
+					resource.TestCheckResourceAttrWith("sealedsecret.db", "yaml_content", checkUnsealsTo(cluster, "secret_aaa")),
+				),
+			},
+			{
+				Config:      cluster.ProviderConfig(`certificate_fingerprint = "SHA256:unknown"`) + config,
+				ExpectError: regexp.MustCompile(`serves no certificate with the fingerprint`),
+			},
+		},
+	})
+}
+
+func TestAccCertificateURL(t *testing.T) {
+	cluster := acctest.NewCluster(t)
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(cluster.CertPEM())
+	}))
+	defer server.Close()
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+
+	resource.Test(t, resource.TestCase{
+		ProtoV5ProviderFactories: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				// no kubernetes block, the cluster is never asked
+				Config: fmt.Sprintf(`
+provider "sealedsecret" {
+  certificate_url                = %q
+  certificate_url_ca_certificate = %q
+}
+`, server.URL+"/cert.pem", caPEM) + testAccSealedSecretConfig,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("sealedsecret.db", "sealed_with_fingerprint", cluster.Fingerprints()[0]),
+					resource.TestCheckResourceAttrWith("sealedsecret.db", "yaml_content", checkUnsealsTo(cluster, "secret_aaa")),
+				),
+			},
+		},
+	})
+}
+
+func TestAccCertificateSourceSecret(t *testing.T) {
+	cluster := acctest.NewCluster(t)
+	cluster.RotateKey(t)
+	// the provider must not need the controller's service
+	cluster.DisableService()
+
+	resource.Test(t, resource.TestCase{
+		ProtoV5ProviderFactories: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: cluster.ProviderConfig(`certificate_source = "secret"`) + testAccSealedSecretConfig,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("sealedsecret.db", "sealed_with_fingerprint", cluster.Fingerprints()[1]),
+					resource.TestCheckResourceAttrWith("sealedsecret.db", "yaml_content", func(v string) error {
+						if !cluster.SealedWithLatestKey([]byte(v)) {
+							return fmt.Errorf("expected the secret to be sealed with the latest key")
+						}
+						return nil
+					}),
+				),
+			},
+		},
+	})
+}
@@ -0,0 +1,124 @@
+package provider
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/jifwin/terraform-provider-sealedsecret/internal/kubeseal"
+	certUtil "k8s.io/client-go/util/cert"
+)
+
+const (
+	certificates = "certificates"
+	currentFpr   = "current_fingerprint"
+	fingerprint  = "fingerprint"
+	notBefore    = "not_before"
+	notAfter     = "not_after"
+	certPEM      = "pem"
+	current      = "current"
+)
+
+var _ datasource.DataSourceWithConfigure = &certificatesDataSource{}
+
+type certificatesDataSource struct {
+	provider *ProviderConfig
+}
+
+type certificatesModel struct {
+	Certificates []certificateModel `tfsdk:"certificates"`
+	CurrentFpr   types.String       `tfsdk:"current_fingerprint"`
+}
+
+type certificateModel struct {
+	Fingerprint types.String `tfsdk:"fingerprint"`
+	NotBefore   types.String `tfsdk:"not_before"`
+	NotAfter    types.String `tfsdk:"not_after"`
+	PEM         types.String `tfsdk:"pem"`
+	Current     types.Bool   `tfsdk:"current"`
+}
+
+func newCertificatesDataSource() datasource.DataSource {
+	return &certificatesDataSource{}
+}
+
+func (d *certificatesDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_certificates"
+}
+
+func (d *certificatesDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Lists the certificates of all keys the controller serves, oldest first. An expired certificate is reported as a warning, also with reject_expired_certificate.",
+		Attributes: map[string]schema.Attribute{
+			certificates: schema.ListAttribute{
+				Computed: true,
+				ElementType: types.ObjectType{AttrTypes: map[string]attr.Type{
+					fingerprint: types.StringType,
+					notBefore:   types.StringType,
+					notAfter:    types.StringType,
+					certPEM:     types.StringType,
+					current:     types.BoolType,
+				}},
+				Description: "The certificates with their fingerprint, validity in RFC 3339, PEM encoding and whether secrets are sealed with it.",
+			},
+			currentFpr: schema.StringAttribute{
+				Computed:    true,
+				Description: "Fingerprint of the certificate secrets are sealed with, the newest one unless the provider pins certificate_fingerprint.",
+			},
+		},
+	}
+}
+
+func (d *certificatesDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+	d.provider = req.ProviderData.(*ProviderConfig)
+}
+
+func (d *certificatesDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	ctx = withLogging(ctx, map[string]interface{}{"resource_address": "data.sealedsecret_certificates"})
+	// waits for the controller and checks a pinned fingerprint, the certificates are cached afterwards
+	pk, expiry, err := resolvePublicKey(ctx, d.provider)
+	if err != nil && !errors.As(err, &expiry) {
+		resp.Diagnostics.AddError("Unable to fetch the public key", err.Error())
+		return
+	}
+	var currentFingerprint string
+	switch {
+	case pk != nil:
+		currentFingerprint = kubeseal.Fingerprint(pk)
+	case expiry != nil:
+		// only sealing is refused with reject_expired_certificate, listing the certificates is not
+		currentFingerprint = expiry.Fingerprint
+	}
+	resp.Diagnostics.Append(expiryDiagnostics(expiry, false)...)
+	cached, err := d.provider.Certificates(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to fetch the certificates", err.Error())
+		return
+	}
+	certs := append([]*x509.Certificate(nil), cached...)
+	sort.SliceStable(certs, func(i, j int) bool { return certs[i].NotBefore.Before(certs[j].NotBefore) })
+
+	state := certificatesModel{CurrentFpr: types.StringValue(currentFingerprint)}
+	for _, c := range certs {
+		fpr := kubeseal.Fingerprint(c.PublicKey.(*rsa.PublicKey))
+		state.Certificates = append(state.Certificates, certificateModel{
+			Fingerprint: types.StringValue(fpr),
+			NotBefore:   types.StringValue(c.NotBefore.UTC().Format(time.RFC3339)),
+			NotAfter:    types.StringValue(c.NotAfter.UTC().Format(time.RFC3339)),
+			PEM:         types.StringValue(string(pem.EncodeToMemory(&pem.Block{Type: certUtil.CertificateBlockType, Bytes: c.Raw}))),
+			Current:     types.BoolValue(fpr == state.CurrentFpr.ValueString()),
+		})
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
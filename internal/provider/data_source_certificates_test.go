@@ -0,0 +1,80 @@
+package provider
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/jifwin/terraform-provider-sealedsecret/internal/kubeseal"
+	"github.com/stretchr/testify/assert"
+)
+
+func testCertValidity(t *testing.T, notBefore, notAfter time.Time) *x509.Certificate {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "sealed-secret"},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestCertificatesDataSourceRead(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	older := testCertValidity(t, now.Add(-60*24*time.Hour), now.Add(300*24*time.Hour))
+	newer := testCertValidity(t, now.Add(-24*time.Hour), now.Add(-time.Hour))
+
+	provider := testProviderConfig(t, nil)
+	// the controller does not serve its certificates in any particular order
+	provider.Certificates = func(ctx context.Context) ([]*x509.Certificate, error) {
+		return []*x509.Certificate{newer, older}, nil
+	}
+	provider.PublicKeyResolver = kubeseal.SelectPK(provider.Certificates, "", kubeseal.ValidityPolicy{RejectExpired: true})
+
+	d := &certificatesDataSource{provider: provider}
+	var schemaResp datasource.SchemaResponse
+	d.Schema(ctx, datasource.SchemaRequest{}, &schemaResp)
+	resp := datasource.ReadResponse{State: tfsdk.State{
+		Schema: schemaResp.Schema,
+		Raw:    tftypes.NewValue(schemaResp.Schema.Type().TerraformType(ctx), nil),
+	}}
+	d.Read(ctx, datasource.ReadRequest{}, &resp)
+
+	// reject_expired_certificate only refuses sealing, the data source still lists the expired certificate
+	assert.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+	if assert.Equal(t, 1, resp.Diagnostics.WarningsCount()) {
+		assert.Equal(t, "Controller certificate expired", resp.Diagnostics.Warnings()[0].Summary())
+	}
+
+	var state certificatesModel
+	assert.False(t, resp.State.Get(ctx, &state).HasError())
+	newerFpr := kubeseal.Fingerprint(newer.PublicKey.(*rsa.PublicKey))
+	assert.Equal(t, newerFpr, state.CurrentFpr.ValueString())
+	if assert.Len(t, state.Certificates, 2) {
+		assert.Equal(t, kubeseal.Fingerprint(older.PublicKey.(*rsa.PublicKey)), state.Certificates[0].Fingerprint.ValueString())
+		assert.False(t, state.Certificates[0].Current.ValueBool())
+		assert.Equal(t, newerFpr, state.Certificates[1].Fingerprint.ValueString())
+		assert.True(t, state.Certificates[1].Current.ValueBool())
+	}
+}
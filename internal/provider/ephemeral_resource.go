@@ -0,0 +1,111 @@
+package provider
+
+import (
+	"context"
+
+	ssv1alpha1 "github.com/bitnami-labs/sealed-secrets/pkg/apis/sealedsecrets/v1alpha1"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/jifwin/terraform-provider-sealedsecret/internal/k8s"
+)
+
+var _ ephemeral.EphemeralResourceWithConfigure = &sealedSecretEphemeralResource{}
+
+type sealedSecretEphemeralResource struct {
+	provider *ProviderConfig
+}
+
+type sealedSecretEphemeralModel struct {
+	Name        types.String `tfsdk:"name"`
+	Namespace   types.String `tfsdk:"namespace"`
+	Type        types.String `tfsdk:"type"`
+	Data        types.Map    `tfsdk:"data"`
+	YamlContent types.String `tfsdk:"yaml_content"`
+	PublicKey   types.String `tfsdk:"public_key"`
+}
+
+func newSealedSecretEphemeralResource() ephemeral.EphemeralResource {
+	return &sealedSecretEphemeralResource{}
+}
+
+func (r *sealedSecretEphemeralResource) Metadata(ctx context.Context, req ephemeral.MetadataRequest, resp *ephemeral.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName
+}
+
+func (r *sealedSecretEphemeralResource) Schema(ctx context.Context, req ephemeral.SchemaRequest, resp *ephemeral.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Seals a secret without storing anything in the state, e.g. for values read from ephemeral sources like Vault.",
+		Attributes: map[string]schema.Attribute{
+			name: schema.StringAttribute{
+				Required:    true,
+				Validators:  []validator.String{secretNameValidator{}},
+				Description: "name of the secret",
+			},
+			namespace: schema.StringAttribute{
+				Required:    true,
+				Validators:  []validator.String{namespaceValidator{}},
+				Description: "namespace of the secret",
+			},
+			secretType: schema.StringAttribute{
+				Optional:    true,
+				Computed:    true,
+				Description: "The secret type (ex. Opaque). Default type is Opaque.",
+			},
+			data: schema.MapAttribute{
+				ElementType: types.StringType,
+				Required:    true,
+				Sensitive:   true,
+				Validators:  []validator.Map{secretDataValidator{}},
+				Description: "Key/value pairs to populate the secret. The value will be base64 encoded",
+			},
+			yaml_content: schema.StringAttribute{
+				Computed:    true,
+				Description: "The produced sealed secret yaml file.",
+			},
+			public_key: schema.StringAttribute{
+				Computed:    true,
+				Description: "The key used for encryption",
+			},
+		},
+	}
+}
+
+func (r *sealedSecretEphemeralResource) Configure(ctx context.Context, req ephemeral.ConfigureRequest, resp *ephemeral.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+	r.provider = req.ProviderData.(*ProviderConfig)
+}
+
+func (r *sealedSecretEphemeralResource) Open(ctx context.Context, req ephemeral.OpenRequest, resp *ephemeral.OpenResponse) {
+	var cfg sealedSecretEphemeralModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &cfg)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	manifest := k8s.SecretManifest{
+		Name:      cfg.Name.ValueString(),
+		Namespace: cfg.Namespace.ValueString(),
+		Type:      stringOrDefault(cfg.Type, defaultType),
+	}
+	resp.Diagnostics.Append(cfg.Data.ElementsAs(ctx, &manifest.Data, false)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx = withLogging(ctx, secretFields("sealedsecret", manifest.Namespace, manifest.Name))
+	ctx = maskSecretValues(ctx, manifest.Data)
+
+	sealedSecret, pk, err := createSealedSecret(ctx, r.provider, &manifest, ssv1alpha1.StrictScope)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to create the sealed secret", err.Error())
+		return
+	}
+
+	cfg.Type = types.StringValue(manifest.Type)
+	cfg.YamlContent = types.StringValue(string(sealedSecret))
+	cfg.PublicKey = types.StringValue(formatPublicKeyAsString(pk))
+	resp.Diagnostics.Append(resp.Result.Set(ctx, &cfg)...)
+}
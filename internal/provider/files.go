@@ -0,0 +1,83 @@
+package provider
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/jifwin/terraform-provider-sealedsecret/internal/k8s"
+)
+
+// readDataFiles reads the files keyed by secret key, every regular file of dir keyed by its name, like
+// kubectl create secret --from-file, and the KEY=VALUE lines of envFile. Subdirectories and symlinks in dir are skipped.
+func readDataFiles(files map[string]string, dir, envFile string) (map[string]string, error) {
+	values := make(map[string]string, len(files))
+	for key, p := range files {
+		if err := k8s.ValidateKey(key); err != nil {
+			return nil, err
+		}
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the file of key %s: %w", key, err)
+		}
+		values[key] = string(content)
+	}
+
+	if dir != "" {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read directory %s: %w", dir, err)
+		}
+		fromDir := make(map[string]string, len(entries))
+		for _, entry := range entries {
+			if !entry.Type().IsRegular() {
+				continue
+			}
+			key := entry.Name()
+			if err := k8s.ValidateKey(key); err != nil {
+				return nil, fmt.Errorf("file in %s: %w", dir, err)
+			}
+			content, err := os.ReadFile(filepath.Join(dir, key))
+			if err != nil {
+				return nil, err
+			}
+			fromDir[key] = string(content)
+		}
+		if err := addData(values, fromDir, dataFromDir); err != nil {
+			return nil, err
+		}
+	}
+
+	if envFile != "" {
+		f, err := os.Open(envFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read env file: %w", err)
+		}
+		defer f.Close()
+		fromEnv, err := k8s.ParseEnvFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("env file %s: %w", envFile, err)
+		}
+		if err := addData(values, fromEnv, dataFromEnvFile); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+// addData adds the values of source to data, a key may only be set once.
+func addData(data, values map[string]string, source string) error {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if _, ok := data[k]; ok {
+			return fmt.Errorf("key %s is set more than once, again by %s", k, source)
+		}
+		data[k] = values[k]
+	}
+	return nil
+}
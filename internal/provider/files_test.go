@@ -0,0 +1,153 @@
+package provider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFile(t *testing.T, p, content string) {
+	if err := os.WriteFile(p, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadDataFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "tls.crt"), "crt_aaa")
+	writeFile(t, filepath.Join(dir, "tls.key"), "key_aaa")
+	assert.Nil(t, os.Mkdir(filepath.Join(dir, "nested"), 0o700))
+	single := filepath.Join(t.TempDir(), "ca.crt")
+	writeFile(t, single, "ca_aaa")
+	envFile := filepath.Join(t.TempDir(), ".env")
+	writeFile(t, envFile, "USER=user_aaa\n")
+
+	tests := []struct {
+		Name        string
+		Files       map[string]string
+		Dir         string
+		EnvFile     string
+		Expected    map[string]string
+		ExpectedErr string
+	}{
+		{
+			Name:     "files, directory and env file",
+			Files:    map[string]string{"ca.crt": single},
+			Dir:      dir,
+			EnvFile:  envFile,
+			Expected: map[string]string{"ca.crt": "ca_aaa", "tls.crt": "crt_aaa", "tls.key": "key_aaa", "USER": "user_aaa"},
+		},
+		{
+			Name:        "key set by a file and the env file",
+			Files:       map[string]string{"USER": single},
+			EnvFile:     envFile,
+			ExpectedErr: "key USER is set more than once, again by data_from_env_file",
+		},
+		{
+			Name:        "key set twice",
+			Files:       map[string]string{"tls.crt": single},
+			Dir:         dir,
+			ExpectedErr: "key tls.crt is set more than once, again by data_from_directory",
+		},
+		{
+			Name:        "missing file",
+			Files:       map[string]string{"ca.crt": filepath.Join(dir, "missing")},
+			ExpectedErr: "unable to read the file of key ca.crt",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			files, err := readDataFiles(tc.Files, tc.Dir, tc.EnvFile)
+			if tc.ExpectedErr != "" {
+				assert.ErrorContains(t, err, tc.ExpectedErr)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.Expected, files)
+		})
+	}
+}
+
+func TestModifyPlanDataFiles(t *testing.T) {
+	ctx := context.Background()
+	pk := testPublicKey(t)
+	r := &sealedSecretResource{provider: testProviderConfig(t, pk)}
+	s := resourceSchema(t, r).Schema
+
+	file := filepath.Join(t.TempDir(), "ca.crt")
+	writeFile(t, file, "ca_aaa")
+	hashes, err := hmacData(r.provider.HMACKey, map[string]string{"ca.crt": "ca_aaa"})
+	assert.Nil(t, err)
+	state := sealedSecretModel{
+		ID:              types.StringValue("name_aaa"),
+		Name:            types.StringValue("name_aaa"),
+		Namespace:       types.StringValue("ns_aaa"),
+		Type:            types.StringValue(defaultType),
+		Data:            types.MapNull(types.StringType),
+		DataWO:          types.MapNull(types.StringType),
+		DataFiles:       stringMap(t, map[string]string{"ca.crt": file}),
+		DataFilesSha256: stringMap(t, hashes),
+		HashData:        types.BoolValue(false),
+		DataHmac:        types.MapNull(types.StringType),
+		EncryptedData:   stringMap(t, map[string]string{"ca.crt": "sealed_aaa"}),
+		YamlContent:     types.StringValue("yaml_aaa"),
+		PublicKey:       types.StringValue(formatPublicKeyAsString(pk)),
+	}
+	priorState := tfsdk.State{Schema: s}
+	assert.False(t, priorState.Set(ctx, &state).HasError())
+
+	modifyPlan := func() resource.ModifyPlanResponse {
+		req := resource.ModifyPlanRequest{
+			Config: tfsdk.Config{Schema: s, Raw: priorState.Raw},
+			Plan:   tfsdk.Plan{Schema: s, Raw: priorState.Raw},
+			State:  priorState,
+		}
+		resp := modifyPlanResponse(req.Plan)
+		r.ModifyPlan(ctx, req, &resp)
+		assert.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+		return resp
+	}
+
+	assert.Empty(t, modifyPlan().RequiresReplace)
+
+	writeFile(t, file, "ca_bbb")
+	assert.Equal(t, path.Paths{path.Root(dataFilesSha256)}, modifyPlan().RequiresReplace)
+
+	// the contents are never stored unkeyed, so the provider refuses to read files without hmac_key
+	r.provider.HMACKey = nil
+	req := resource.ModifyPlanRequest{
+		Config: tfsdk.Config{Schema: s, Raw: priorState.Raw},
+		Plan:   tfsdk.Plan{Schema: s, Raw: priorState.Raw},
+		State:  priorState,
+	}
+	resp := modifyPlanResponse(req.Plan)
+	r.ModifyPlan(ctx, req, &resp)
+	if assert.True(t, resp.Diagnostics.HasError()) {
+		assert.Contains(t, resp.Diagnostics[0].Detail(), errMissingHMACKey.Error())
+	}
+}
+
+func TestFilesSha256EnvFile(t *testing.T) {
+	ctx := context.Background()
+	envFile := filepath.Join(t.TempDir(), ".env")
+	writeFile(t, envFile, "PIN=1234\nPASSWORD=hunter2\n")
+	files, err := readDataFiles(nil, "", envFile)
+	assert.Nil(t, err)
+
+	var diags diag.Diagnostics
+	hashes := filesSha256(ctx, []byte("key_aaa"), files, &diags)
+	assert.False(t, diags.HasError())
+	assert.NotEqual(t, filesSha256(ctx, []byte("key_bbb"), files, &diags), hashes)
+
+	filesSha256(ctx, nil, files, &diags)
+	assert.True(t, diags.HasError())
+}
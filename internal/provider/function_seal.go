@@ -0,0 +1,84 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/jifwin/terraform-provider-sealedsecret/internal/kubeseal"
+)
+
+var _ function.Function = &sealFunction{}
+
+const sealDescription = "Encrypts value with the public key of the given certificate and returns the base64 encoded ciphertext. " +
+	"name is ignored for the namespace-wide scope, name and namespace for the cluster-wide scope."
+
+// deterministicWarning is shown for every function that seals, as their ciphertext can be used to confirm guesses.
+const deterministicWarning = "functions must be deterministic, so the ciphertext is derived from the arguments and the same " +
+	"value always produces the same ciphertext. Anyone holding the public certificate can confirm a guessed value against it, " +
+	"so do not seal low-entropy values such as passwords or PINs with this function, use the sealedsecret resource instead."
+
+type sealFunction struct{}
+
+func newSealFunction() function.Function {
+	return &sealFunction{}
+}
+
+func (f *sealFunction) Metadata(ctx context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "seal"
+}
+
+func (f *sealFunction) Definition(ctx context.Context, req function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary:             "Seals a single value offline, like kubeseal --raw.",
+		Description:         sealDescription + " WARNING: " + deterministicWarning,
+		MarkdownDescription: sealDescription + "\n\n!> **Warning:** " + deterministicWarning,
+		Parameters: []function.Parameter{
+			certPEMParameter(),
+			function.StringParameter{Name: namespace, Description: "Namespace of the secret."},
+			function.StringParameter{Name: name, Description: "Name of the secret."},
+			function.StringParameter{Name: "value", Description: "The value to seal."},
+			scopeParameter(),
+		},
+		Return: function.StringReturn{},
+	}
+}
+
+func (f *sealFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var certPEM, ns, n, value, scopeName string
+	resp.Error = req.Arguments.Get(ctx, &certPEM, &ns, &n, &value, &scopeName)
+	if resp.Error != nil {
+		return
+	}
+
+	pk, err := kubeseal.ParsePK([]byte(certPEM))
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(0, "Unable to parse the certificate: "+err.Error())
+		return
+	}
+	scope, err := kubeseal.ParseScope(scopeName)
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(4, err.Error())
+		return
+	}
+
+	sealed, err := kubeseal.SealValueDeterministic(pk, ns, n, scope, []byte(value))
+	if err != nil {
+		resp.Error = function.NewFuncError(err.Error())
+		return
+	}
+	resp.Error = resp.Result.Set(ctx, sealed)
+}
+
+func certPEMParameter() function.Parameter {
+	return function.StringParameter{
+		Name:        "cert_pem",
+		Description: "PEM encoded certificate of the sealed-secrets controller, e.g. the output of kubeseal --fetch-cert.",
+	}
+}
+
+func scopeParameter() function.Parameter {
+	return function.StringParameter{
+		Name:        "scope",
+		Description: "The sealing scope: strict, namespace-wide or cluster-wide. An empty string means strict.",
+	}
+}
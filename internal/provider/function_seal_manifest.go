@@ -0,0 +1,78 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/jifwin/terraform-provider-sealedsecret/internal/k8s"
+	"github.com/jifwin/terraform-provider-sealedsecret/internal/kubeseal"
+)
+
+var _ function.Function = &sealManifestFunction{}
+
+const sealManifestDescription = "Produces the same yaml_content as the sealedsecret resource, using the public key of the given certificate."
+
+type sealManifestFunction struct{}
+
+func newSealManifestFunction() function.Function {
+	return &sealManifestFunction{}
+}
+
+func (f *sealManifestFunction) Metadata(ctx context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "seal_manifest"
+}
+
+func (f *sealManifestFunction) Definition(ctx context.Context, req function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary:             "Seals a secret offline and returns the SealedSecret manifest.",
+		Description:         sealManifestDescription + " WARNING: " + deterministicWarning,
+		MarkdownDescription: sealManifestDescription + "\n\n!> **Warning:** " + deterministicWarning,
+		Parameters: []function.Parameter{
+			certPEMParameter(),
+			function.StringParameter{Name: namespace, Description: "Namespace of the secret."},
+			function.StringParameter{Name: name, Description: "Name of the secret."},
+			function.StringParameter{Name: secretType, Description: "The secret type (ex. Opaque). An empty string means Opaque."},
+			function.MapParameter{Name: data, ElementType: types.StringType, Description: "Key/value pairs to populate the secret."},
+			scopeParameter(),
+		},
+		Return: function.StringReturn{},
+	}
+}
+
+func (f *sealManifestFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var certPEM, scopeName string
+	var manifest k8s.SecretManifest
+	resp.Error = req.Arguments.Get(ctx, &certPEM, &manifest.Namespace, &manifest.Name, &manifest.Type, &manifest.Data, &scopeName)
+	if resp.Error != nil {
+		return
+	}
+	if manifest.Type == "" {
+		manifest.Type = defaultType
+	}
+
+	pk, err := kubeseal.ParsePK([]byte(certPEM))
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(0, "Unable to parse the certificate: "+err.Error())
+		return
+	}
+	scope, err := kubeseal.ParseScope(scopeName)
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(5, err.Error())
+		return
+	}
+
+	secret, err := k8s.CreateSecret(&manifest)
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(4, err.Error())
+		return
+	}
+	kubeseal.SetScope(&secret, scope)
+
+	sealedSecret, err := kubeseal.SealSecretDeterministic(secret, pk)
+	if err != nil {
+		resp.Error = function.NewFuncError(err.Error())
+		return
+	}
+	resp.Error = resp.Result.Set(ctx, string(sealedSecret))
+}
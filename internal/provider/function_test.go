@@ -0,0 +1,89 @@
+package provider
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	ssv1alpha1 "github.com/bitnami-labs/sealed-secrets/pkg/apis/sealedsecrets/v1alpha1"
+	"github.com/bitnami-labs/sealed-secrets/pkg/crypto"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+func testCertificate(t *testing.T) (*rsa.PrivateKey, string) {
+	key, cert, err := crypto.GeneratePrivateKeyAndCert(2048, time.Hour, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+}
+
+func runFunction(t *testing.T, f function.Function, args ...attr.Value) (string, *function.FuncError) {
+	resp := function.RunResponse{Result: function.NewResultData(types.StringUnknown())}
+	f.Run(context.Background(), function.RunRequest{Arguments: function.NewArgumentsData(args)}, &resp)
+
+	var result types.String
+	if resp.Error == nil {
+		result = resp.Result.Value().(types.String)
+	}
+	return result.ValueString(), resp.Error
+}
+
+func TestSealFunction(t *testing.T) {
+	key, certPEM := testCertificate(t)
+
+	sealed, funcErr := runFunction(t, newSealFunction(),
+		types.StringValue(certPEM), types.StringValue("ns_aaa"), types.StringValue("name_aaa"),
+		types.StringValue("secret_aaa"), types.StringValue("namespace-wide"))
+	assert.Nil(t, funcErr)
+
+	ciphertext, err := base64.StdEncoding.DecodeString(sealed)
+	assert.Nil(t, err)
+	plaintext, err := crypto.HybridDecrypt(rand.Reader, map[string]*rsa.PrivateKey{"": key}, ciphertext, []byte("ns_aaa"))
+	assert.Nil(t, err)
+	assert.Equal(t, "secret_aaa", string(plaintext))
+
+	_, funcErr = runFunction(t, newSealFunction(),
+		types.StringValue("not a certificate"), types.StringValue("ns_aaa"), types.StringValue("name_aaa"),
+		types.StringValue("secret_aaa"), types.StringValue(""))
+	assert.NotNil(t, funcErr)
+	assert.Equal(t, int64(0), *funcErr.FunctionArgument)
+}
+
+func TestSealManifestFunction(t *testing.T) {
+	key, certPEM := testCertificate(t)
+
+	manifest, funcErr := runFunction(t, newSealManifestFunction(),
+		types.StringValue(certPEM), types.StringValue("ns_aaa"), types.StringValue("name_aaa"), types.StringValue(""),
+		types.MapValueMust(types.StringType, map[string]attr.Value{"secret": types.StringValue("secret_aaa")}),
+		types.StringValue("cluster-wide"))
+	assert.Nil(t, funcErr)
+
+	var ss ssv1alpha1.SealedSecret
+	assert.Nil(t, yaml.Unmarshal([]byte(manifest), &ss))
+	assert.Equal(t, "name_aaa", ss.Name)
+	assert.Equal(t, "ns_aaa", ss.Namespace)
+	assert.Equal(t, ssv1alpha1.ClusterWideScope, ss.Scope())
+	assert.Equal(t, defaultType, string(ss.Spec.Template.Type))
+
+	ciphertext, err := base64.StdEncoding.DecodeString(ss.Spec.EncryptedData["secret"])
+	assert.Nil(t, err)
+	plaintext, err := crypto.HybridDecrypt(rand.Reader, map[string]*rsa.PrivateKey{"": key}, ciphertext, []byte(""))
+	assert.Nil(t, err)
+	assert.Equal(t, "secret_aaa", string(plaintext))
+
+	_, funcErr = runFunction(t, newSealManifestFunction(),
+		types.StringValue(certPEM), types.StringValue("ns_aaa"), types.StringValue("name_aaa"), types.StringValue(""),
+		types.MapValueMust(types.StringType, map[string]attr.Value{}),
+		types.StringValue(""))
+	assert.NotNil(t, funcErr)
+	assert.Equal(t, int64(4), *funcErr.FunctionArgument)
+}
@@ -0,0 +1,32 @@
+package provider
+
+import (
+	"fmt"
+	"strings"
+
+	ssv1alpha1 "github.com/bitnami-labs/sealed-secrets/pkg/apis/sealedsecrets/v1alpha1"
+	sigsyaml "sigs.k8s.io/yaml"
+)
+
+// encryptedDataOf returns spec.encryptedData of a SealedSecret manifest.
+func encryptedDataOf(manifest []byte) (map[string]string, error) {
+	var ss ssv1alpha1.SealedSecret
+	if err := sigsyaml.Unmarshal(manifest, &ss); err != nil {
+		return nil, fmt.Errorf("unable to decode the sealed secret: %w", err)
+	}
+	return ss.Spec.EncryptedData, nil
+}
+
+// helmValuesFor renders the encrypted data as yaml nested under the dot separated keyPath.
+func helmValuesFor(keyPath string, encrypted map[string]string) (string, error) {
+	keys := strings.Split(keyPath, ".")
+	var values interface{} = encrypted
+	for i := len(keys) - 1; i >= 0; i-- {
+		if keys[i] == "" {
+			return "", fmt.Errorf("invalid key path %q: empty key", keyPath)
+		}
+		values = map[string]interface{}{keys[i]: values}
+	}
+	out, err := sigsyaml.Marshal(values)
+	return string(out), err
+}
@@ -0,0 +1,76 @@
+package provider
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var errMissingHMACKey = errors.New("the provider hmac_key (or SEALEDSECRET_HMAC_KEY) must be set to hash the secret data")
+
+// hmacData returns a HMAC-SHA256 of every key/value pair, so changes to the
+// plaintext can be detected without keeping it in the state.
+func hmacData(key []byte, data map[string]string) (map[string]string, error) {
+	if len(key) == 0 {
+		return nil, errMissingHMACKey
+	}
+
+	hashes := make(map[string]string, len(data))
+	for k, v := range data {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(k))
+		mac.Write([]byte{0})
+		mac.Write([]byte(v))
+		hashes[k] = hex.EncodeToString(mac.Sum(nil))
+	}
+	return hashes, nil
+}
+
+// hashDataWO returns the data_hmac of data_wo.
+func hashDataWO(ctx context.Context, key []byte, dataWO types.Map) (types.Map, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	values := make(map[string]string)
+	diags.Append(dataWO.ElementsAs(ctx, &values, false)...)
+	if diags.HasError() {
+		return types.MapNull(types.StringType), diags
+	}
+
+	hashes, err := hmacData(key, values)
+	if err != nil {
+		diags.AddAttributeError(path.Root(hashData), "Unable to hash data_wo", err.Error())
+		return types.MapNull(types.StringType), diags
+	}
+	m, d := types.MapValueFrom(ctx, types.StringType, hashes)
+	diags.Append(d...)
+	return m, diags
+}
+
+// hashBundleDataWO returns the data_hmac of a bundle's data_wo, keyed by secret name like data_wo.
+func hashBundleDataWO(ctx context.Context, key []byte, dataWO types.Map) (types.Map, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	secretType := types.MapType{ElemType: types.StringType}
+	values := make(map[string]map[string]string)
+	diags.Append(dataWO.ElementsAs(ctx, &values, false)...)
+	if diags.HasError() {
+		return types.MapNull(secretType), diags
+	}
+
+	hashes := make(map[string]map[string]string, len(values))
+	for name, data := range values {
+		secretHashes, err := hmacData(key, data)
+		if err != nil {
+			diags.AddAttributeError(path.Root(hashData), "Unable to hash data_wo", err.Error())
+			return types.MapNull(secretType), diags
+		}
+		hashes[name] = secretHashes
+	}
+	m, d := types.MapValueFrom(ctx, secretType, hashes)
+	diags.Append(d...)
+	return m, diags
+}
@@ -0,0 +1,53 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/jifwin/terraform-provider-sealedsecret/internal/k8s"
+	"github.com/jifwin/terraform-provider-sealedsecret/internal/kubeseal"
+)
+
+// logLevelEnv is the prefix of the variables setting the level of a subsystem, e.g. TF_LOG_PROVIDER_SEALEDSECRET_K8S.
+const logLevelEnv = "TF_LOG_PROVIDER_SEALEDSECRET"
+
+var logSubsystems = []string{k8s.LogSubsystem, kubeseal.LogSubsystem}
+
+// withLogging sets fields on the provider logger and creates the subsystem loggers, which inherit them.
+func withLogging(ctx context.Context, fields map[string]interface{}) context.Context {
+	for k, v := range fields {
+		ctx = tflog.SetField(ctx, k, v)
+	}
+	for _, subsystem := range logSubsystems {
+		ctx = tflog.NewSubsystem(ctx, subsystem, tflog.WithRootFields(), tflog.WithLevelFromEnv(logLevelEnv, subsystem))
+	}
+	return ctx
+}
+
+// maskSecretValues masks the values in the messages and fields of the provider and subsystem loggers.
+func maskSecretValues(ctx context.Context, values map[string]string) context.Context {
+	secrets := make([]string, 0, len(values))
+	for _, v := range values {
+		if v != "" {
+			secrets = append(secrets, v)
+		}
+	}
+	if len(secrets) == 0 {
+		return ctx
+	}
+	ctx = tflog.MaskLogStrings(ctx, secrets...)
+	for _, subsystem := range logSubsystems {
+		ctx = tflog.SubsystemMaskLogStrings(ctx, subsystem, secrets...)
+	}
+	return ctx
+}
+
+// secretFields identifies a secret and the resource sealing it in the log. Terraform does not pass the configuration
+// address to providers, so the resource is identified by its type and secret, like in the warnings.
+func secretFields(resourceType, namespace, name string) map[string]interface{} {
+	return map[string]interface{}{
+		"namespace":        namespace,
+		"name":             name,
+		"resource_address": resourceType + " " + namespace + "/" + name,
+	}
+}
@@ -0,0 +1,80 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-log/tflogtest"
+	"github.com/jifwin/terraform-provider-sealedsecret/internal/k8s"
+	"github.com/jifwin/terraform-provider-sealedsecret/internal/kubeseal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoggingMasksSecretValues(t *testing.T) {
+	var out bytes.Buffer
+	ctx := withLogging(tflogtest.RootLogger(context.Background(), &out), secretFields("sealedsecret", "ns-aaa", "name-aaa"))
+	ctx = maskSecretValues(ctx, map[string]string{"password": "secret_aaa", "empty": ""})
+
+	tflog.Debug(ctx, "root secret_aaa", map[string]interface{}{"value": "secret_aaa"})
+	tflog.SubsystemDebug(ctx, k8s.LogSubsystem, "subsystem secret_aaa", map[string]interface{}{"value": "secret_aaa"})
+
+	assert.NotContains(t, out.String(), "secret_aaa")
+	entries, err := tflogtest.MultilineJSONDecode(&out)
+	assert.Nil(t, err)
+	assert.Len(t, entries, 2)
+	for _, e := range entries {
+		assert.Equal(t, "ns-aaa", e["namespace"])
+		assert.Equal(t, "name-aaa", e["name"])
+		assert.Equal(t, "sealedsecret ns-aaa/name-aaa", e["resource_address"])
+		assert.Equal(t, "***", e["value"])
+	}
+	assert.Equal(t, "provider.k8s", entries[1]["@module"])
+}
+
+func TestBundleModifyPlanLogging(t *testing.T) {
+	var out bytes.Buffer
+	ctx := tflogtest.RootLogger(context.Background(), &out)
+	pk := testPublicKey(t)
+	provider := testProviderConfig(t, pk)
+	provider.PublicKeyResolver = func(ctx context.Context) (*rsa.PublicKey, error) {
+		tflog.SubsystemDebug(ctx, kubeseal.LogSubsystem, "Resolving the public key")
+		return pk, nil
+	}
+	r := &sealedSecretBundleResource{provider: provider}
+	s := resourceSchema(t, r).Schema
+	plan := tfsdk.Plan{Schema: s}
+	assert.False(t, plan.Set(ctx, &sealedSecretBundleModel{
+		ID:                types.StringUnknown(),
+		Namespace:         types.StringValue("ns-aaa"),
+		Secrets:           []bundleSecretModel{{Name: types.StringValue("name-aaa"), Type: types.StringNull(), Data: stringMap(t, map[string]string{"secret": "secret_aaa"})}},
+		DataWO:            types.MapNull(types.MapType{ElemType: types.StringType}),
+		DataWOVersion:     types.Int64Null(),
+		HashData:          types.BoolValue(false),
+		DataHmac:          types.MapUnknown(types.MapType{ElemType: types.StringType}),
+		Kustomization:     types.BoolValue(false),
+		YamlContent:       types.StringUnknown(),
+		Documents:         types.MapUnknown(types.StringType),
+		KustomizationYaml: types.StringUnknown(),
+		PublicKey:         types.StringUnknown(),
+		SealedWithFpr:     types.StringUnknown(),
+		NeedsReseal:       types.BoolUnknown(),
+	}).HasError())
+
+	resp := modifyPlanResponse(plan)
+	r.ModifyPlan(ctx, resource.ModifyPlanRequest{Plan: plan, State: tfsdk.State{Schema: s, Raw: tftypes.NewValue(s.Type().TerraformType(ctx), nil)}}, &resp)
+	assert.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+
+	entries, err := tflogtest.MultilineJSONDecode(&out)
+	assert.Nil(t, err)
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "provider.kubeseal", entries[0]["@module"])
+		assert.Equal(t, "sealedsecret_bundle ns-aaa", entries[0]["resource_address"])
+	}
+}
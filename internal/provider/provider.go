@@ -3,8 +3,23 @@ package provider
 import (
 	"context"
 	"errors"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/listvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/mapvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/jifwin/terraform-provider-sealedsecret/internal/k8s"
 	"github.com/jifwin/terraform-provider-sealedsecret/internal/kubeseal"
 )
@@ -16,67 +31,274 @@ const (
 	clientKey            = "client_key"
 	token                = "token"
 	clusterCaCertificate = "cluster_ca_certificate"
+	clientCertFile       = "client_certificate_file"
+	clientKeyFile        = "client_key_file"
+	clusterCaCertFile    = "cluster_ca_certificate_file"
+	tokenFile            = "token_file"
+	proxyURL             = "proxy_url"
+	tlsServerName        = "tls_server_name"
+	insecure             = "insecure"
+	requestTimeout       = "request_timeout"
+	connectionMode       = "connection_mode"
+	inCluster            = "in_cluster"
+	impersonateUser      = "impersonate_user"
+	impersonateGroups    = "impersonate_groups"
+	impersonateExtra     = "impersonate_extra"
 	controllerName       = "controller_name"
 	controllerNamespace  = "controller_namespace"
+	multipleControllers  = "multiple_controllers"
+	hmacKey              = "hmac_key"
+	resealOnKeyRotation  = "reseal_on_key_rotation"
+	certificateFpr       = "certificate_fingerprint"
+	minCertValidity      = "min_certificate_validity"
+	rejectExpiredCert    = "reject_expired_certificate"
+	certificateSource    = "certificate_source"
+	certificateURL       = "certificate_url"
+	certificateURLCA     = "certificate_url_ca_certificate"
+	certificateURLCert   = "certificate_url_client_certificate"
+	certificateURLKey    = "certificate_url_client_key"
+	certificateURLHTTP   = "certificate_url_allow_http"
+	cacheDirName         = "terraform-provider-sealedsecret"
+	sourceController     = "controller"
+	sourceSecret         = "secret"
+	modeServiceProxy     = "service-proxy"
+	modePortForward      = "port-forward"
 )
 
-func Provider() *schema.Provider {
-	return &schema.Provider{
-		Schema: map[string]*schema.Schema{
-			kubernetes: {
-				Type:        schema.TypeList,
-				MaxItems:    1,
-				Required:    true,
-				Description: "Kubernetes configuration.",
-				Elem: &schema.Resource{
-					Schema: map[string]*schema.Schema{
-						host: {
-							Type:        schema.TypeString,
-							Required:    true,
-							Description: "The hostname (in form of URI) of Kubernetes master.",
-						},
-						token: {
-							Type:        schema.TypeString,
-							Optional:    true,
-							DefaultFunc: schema.EnvDefaultFunc("KUBE_TOKEN", ""),
-							Description: "Token to authenticate an service account",
-						},
-						clientCertificate: {
-							Type:        schema.TypeString,
-							Optional:    true,
-							DefaultFunc: schema.EnvDefaultFunc("KUBE_CLIENT_CERT_DATA", ""),
-							Description: "PEM-encoded client certificate for TLS authentication.",
-						},
-						clientKey: {
-							Type:        schema.TypeString,
-							Optional:    true,
-							DefaultFunc: schema.EnvDefaultFunc("KUBE_CLIENT_KEY_DATA", ""),
-							Description: "PEM-encoded client certificate key for TLS authentication.",
-						},
-						clusterCaCertificate: {
-							Type:        schema.TypeString,
-							Required:    true,
-							Description: "PEM-encoded root certificates bundle for TLS authentication.",
-						},
-					},
-				},
-			},
-			controllerName: {
-				Type:        schema.TypeString,
+var (
+	_ provider.Provider                       = &sealedSecretProvider{}
+	_ provider.ProviderWithEphemeralResources = &sealedSecretProvider{}
+	_ provider.ProviderWithFunctions          = &sealedSecretProvider{}
+)
+
+type sealedSecretProvider struct {
+	version string
+}
+
+type providerModel struct {
+	Kubernetes          []kubernetesModel `tfsdk:"kubernetes"`
+	ControllerName      types.String      `tfsdk:"controller_name"`
+	ControllerNamespace types.String      `tfsdk:"controller_namespace"`
+	MultipleControllers types.Bool        `tfsdk:"multiple_controllers"`
+	HMACKey             types.String      `tfsdk:"hmac_key"`
+	ResealOnKeyRotation types.Bool        `tfsdk:"reseal_on_key_rotation"`
+	CertificateFpr      types.String      `tfsdk:"certificate_fingerprint"`
+	MinCertValidity     types.String      `tfsdk:"min_certificate_validity"`
+	RejectExpiredCert   types.Bool        `tfsdk:"reject_expired_certificate"`
+	CertificateSource   types.String      `tfsdk:"certificate_source"`
+	CertificateURL      types.String      `tfsdk:"certificate_url"`
+	CertificateURLCA    types.String      `tfsdk:"certificate_url_ca_certificate"`
+	CertificateURLCert  types.String      `tfsdk:"certificate_url_client_certificate"`
+	CertificateURLKey   types.String      `tfsdk:"certificate_url_client_key"`
+	CertificateURLHTTP  types.Bool        `tfsdk:"certificate_url_allow_http"`
+}
+
+type kubernetesModel struct {
+	InCluster            types.Bool   `tfsdk:"in_cluster"`
+	Host                 types.String `tfsdk:"host"`
+	Token                types.String `tfsdk:"token"`
+	TokenFile            types.String `tfsdk:"token_file"`
+	ClientCertificate    types.String `tfsdk:"client_certificate"`
+	ClientCertFile       types.String `tfsdk:"client_certificate_file"`
+	ClientKey            types.String `tfsdk:"client_key"`
+	ClientKeyFile        types.String `tfsdk:"client_key_file"`
+	ClusterCaCertificate types.String `tfsdk:"cluster_ca_certificate"`
+	ClusterCaCertFile    types.String `tfsdk:"cluster_ca_certificate_file"`
+	ProxyURL             types.String `tfsdk:"proxy_url"`
+	TLSServerName        types.String `tfsdk:"tls_server_name"`
+	Insecure             types.Bool   `tfsdk:"insecure"`
+	RequestTimeout       types.String `tfsdk:"request_timeout"`
+	ConnectionMode       types.String `tfsdk:"connection_mode"`
+	ImpersonateUser      types.String `tfsdk:"impersonate_user"`
+	ImpersonateGroups    types.List   `tfsdk:"impersonate_groups"`
+	ImpersonateExtra     types.Map    `tfsdk:"impersonate_extra"`
+}
+
+func New(version string) func() provider.Provider {
+	return func() provider.Provider {
+		return &sealedSecretProvider{version: version}
+	}
+}
+
+func (p *sealedSecretProvider) Metadata(ctx context.Context, req provider.MetadataRequest, resp *provider.MetadataResponse) {
+	resp.TypeName = "sealedsecret"
+	resp.Version = p.version
+}
+
+func (p *sealedSecretProvider) Schema(ctx context.Context, req provider.SchemaRequest, resp *provider.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Attributes: map[string]schema.Attribute{
+			controllerName: schema.StringAttribute{
 				Optional:    true,
 				Description: "The name of the sealed-secret-controller.",
-				Default:     "sealed-data-controller",
 			},
-			controllerNamespace: {
-				Type:        schema.TypeString,
+			controllerNamespace: schema.StringAttribute{
 				Optional:    true,
 				Description: "The namespace the controller is running in.",
-				Default:     "kube-system",
+			},
+			multipleControllers: schema.BoolAttribute{
+				Optional: true,
+				Description: "Set on every provider configuration when the configuration seals for more than one controller, " +
+					"so the ids of the resources get @<controller_namespace>/<controller_name> appended and do not collide.",
+			},
+			hmacKey: schema.StringAttribute{
+				Optional:    true,
+				Sensitive:   true,
+				Description: "Key used to compute the data_hmac of resources with hash_data enabled. Can be set with SEALEDSECRET_HMAC_KEY.",
+			},
+			certificateFpr: schema.StringAttribute{
+				Optional: true,
+				Description: "Seal with the certificate of this fingerprint (e.g. SHA256:abc...) instead of the newest one the controller " +
+					"serves, to stage the rollout of a new key. See the sealedsecret_certificates data source.",
+			},
+			minCertValidity: schema.StringAttribute{
+				Optional: true,
+				Description: "Warn at plan time when the certificate secrets are sealed with expires within this duration, " +
+					"e.g. 720h. Expired certificates are always reported.",
+				Validators: []validator.String{durationValidator{}},
+			},
+			rejectExpiredCert: schema.BoolAttribute{
+				Optional:    true,
+				Description: "Fail instead of sealing with an expired certificate.",
+			},
+			certificateSource: schema.StringAttribute{
+				Optional: true,
+				Description: "Where to read the certificates from: controller (default) fetches them from the controller's " +
+					"HTTP service, secret reads the tls.crt of its key Secrets labelled active in controller_namespace, which " +
+					"only needs get and list on Secrets instead of services/proxy.",
+				Validators: []validator.String{
+					stringvalidator.OneOf(sourceController, sourceSecret),
+					stringvalidator.ConflictsWith(path.MatchRoot(certificateURL)),
+				},
+			},
+			certificateURL: schema.StringAttribute{
+				Optional: true,
+				Description: "Fetch the certificates from this https:// URL or local file path instead of the controller, like " +
+					"kubeseal --cert. The kubernetes block is then only needed by sealedsecret_in_cluster. Downloads are " +
+					"cached and revalidated with their ETag or Last-Modified.",
+			},
+			certificateURLCA: schema.StringAttribute{
+				Optional:    true,
+				Description: "PEM-encoded root certificates bundle to verify the https:// certificate_url server with instead of the system ones.",
+			},
+			certificateURLCert: schema.StringAttribute{
+				Optional:    true,
+				Description: "PEM-encoded client certificate for TLS authentication to the https:// certificate_url server.",
+			},
+			certificateURLKey: schema.StringAttribute{
+				Optional:    true,
+				Sensitive:   true,
+				Description: "PEM-encoded client certificate key for TLS authentication to the https:// certificate_url server.",
+			},
+			certificateURLHTTP: schema.BoolAttribute{
+				Optional: true,
+				Description: "Accept an http:// certificate_url. Anyone on the network path could then replace the certificate " +
+					"and read the secrets sealed with it.",
+			},
+			resealOnKeyRotation: schema.BoolAttribute{
+				Optional: true,
+				Description: "Seal resources again when the controller rotated its key, defaults to true. When false they are only " +
+					"marked with needs_reseal, as the controller keeps the old keys to unseal them.",
 			},
 		},
-		ConfigureContextFunc: configureProvider,
-		ResourcesMap: map[string]*schema.Resource{
-			"sealedsecret": resourceLocal(),
+		Blocks: map[string]schema.Block{
+			kubernetes: schema.ListNestedBlock{
+				Description: "Kubernetes configuration, required unless certificate_url is set.",
+				Validators: []validator.List{
+					listvalidator.SizeAtMost(1),
+				},
+				NestedObject: schema.NestedBlockObject{
+					Attributes: map[string]schema.Attribute{
+						inCluster: schema.BoolAttribute{
+							Optional: true,
+							Description: "Connect with the service account of the pod Terraform runs in, like rest.InClusterConfig. " +
+								"The host, token and CA certificate default to the ones of the pod.",
+						},
+						host: schema.StringAttribute{
+							Optional:    true,
+							Description: "The hostname (in form of URI) of Kubernetes master, required unless in_cluster is set.",
+						},
+						token: schema.StringAttribute{
+							Optional:    true,
+							Sensitive:   true,
+							Description: "Token to authenticate an service account. Can be set with KUBE_TOKEN.",
+							Validators:  []validator.String{stringvalidator.ConflictsWith(path.MatchRelative().AtParent().AtName(tokenFile))},
+						},
+						tokenFile: schema.StringAttribute{
+							Optional:    true,
+							Description: "Path of a file holding the token, e.g. a projected service account token. It is re-read periodically, so rotated tokens are picked up.",
+						},
+						clientCertificate: schema.StringAttribute{
+							Optional:    true,
+							Description: "PEM-encoded client certificate for TLS authentication. Can be set with KUBE_CLIENT_CERT_DATA.",
+							Validators:  []validator.String{stringvalidator.ConflictsWith(path.MatchRelative().AtParent().AtName(clientCertFile))},
+						},
+						clientCertFile: schema.StringAttribute{
+							Optional:    true,
+							Description: "Path of the PEM-encoded client certificate for TLS authentication.",
+						},
+						clientKey: schema.StringAttribute{
+							Optional:    true,
+							Sensitive:   true,
+							Description: "PEM-encoded client certificate key for TLS authentication. Can be set with KUBE_CLIENT_KEY_DATA.",
+							Validators:  []validator.String{stringvalidator.ConflictsWith(path.MatchRelative().AtParent().AtName(clientKeyFile))},
+						},
+						clientKeyFile: schema.StringAttribute{
+							Optional:    true,
+							Description: "Path of the PEM-encoded client certificate key for TLS authentication.",
+						},
+						clusterCaCertificate: schema.StringAttribute{
+							Optional:    true,
+							Description: "PEM-encoded root certificates bundle for TLS authentication.",
+							Validators:  []validator.String{stringvalidator.ConflictsWith(path.MatchRelative().AtParent().AtName(clusterCaCertFile))},
+						},
+						clusterCaCertFile: schema.StringAttribute{
+							Optional:    true,
+							Description: "Path of the PEM-encoded root certificates bundle for TLS authentication.",
+						},
+						proxyURL: schema.StringAttribute{
+							Optional:    true,
+							Description: "URL of the proxy to reach the API server through, e.g. http://proxy.example.com:3128 or socks5://localhost:1080.",
+						},
+						tlsServerName: schema.StringAttribute{
+							Optional:    true,
+							Description: "Server name to verify the API server certificate against instead of the host, e.g. when connecting through a tunnel.",
+						},
+						insecure: schema.BoolAttribute{
+							Optional:    true,
+							Description: "Skip verifying the API server certificate. Only meant for testing.",
+						},
+						requestTimeout: schema.StringAttribute{
+							Optional:    true,
+							Description: "Timeout of a request to the API server, e.g. 30s. Defaults to 10s.",
+							Validators:  []validator.String{durationValidator{}},
+						},
+						connectionMode: schema.StringAttribute{
+							Optional: true,
+							Description: "How to reach the controller: service-proxy (default) goes through the API server's " +
+								"service proxy, port-forward tunnels to a running pod of the controller's service like kubectl " +
+								"port-forward, for clusters where services/proxy is disabled or not permitted.",
+							Validators: []validator.String{stringvalidator.OneOf(modeServiceProxy, modePortForward)},
+						},
+						impersonateUser: schema.StringAttribute{
+							Optional:    true,
+							Description: "User to impersonate, e.g. system:serviceaccount:kube-system:sealed-secrets-reader.",
+						},
+						impersonateGroups: schema.ListAttribute{
+							ElementType: types.StringType,
+							Optional:    true,
+							Description: "Groups to impersonate, requires impersonate_user.",
+							Validators:  []validator.List{listvalidator.AlsoRequires(path.MatchRelative().AtParent().AtName(impersonateUser))},
+						},
+						impersonateExtra: schema.MapAttribute{
+							ElementType: types.ListType{ElemType: types.StringType},
+							Optional:    true,
+							Description: "Extra fields of the impersonated user, e.g. scopes, requires impersonate_user.",
+							Validators:  []validator.Map{mapvalidator.AlsoRequires(path.MatchRelative().AtParent().AtName(impersonateUser))},
+						},
+					},
+				},
+			},
 		},
 	}
 }
@@ -84,40 +306,189 @@ func Provider() *schema.Provider {
 type ProviderConfig struct {
 	ControllerName      string
 	ControllerNamespace string
+	MultipleControllers bool
 	PublicKeyResolver   kubeseal.PKResolverFunc
+	Certificates        kubeseal.CertsResolverFunc
+	HMACKey             []byte
+	SealedSecrets       k8s.SealedSecretClienter
+	ResealOnKeyRotation bool
+
+	targets *secretTargets
+	stale   *staleResources
 }
 
-func configureProvider(ctx context.Context, rd *schema.ResourceData) (interface{}, diag.Diagnostics) {
-	k8sCfg, ok := getMapFromSchemaSet(rd, kubernetes)
-	if !ok {
-		return nil, diag.FromErr(errors.New("k8s configuration is required"))
+func (p *sealedSecretProvider) Configure(ctx context.Context, req provider.ConfigureRequest, resp *provider.ConfigureResponse) {
+	var cfg providerModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &cfg)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if len(cfg.Kubernetes) == 0 && cfg.CertificateURL.IsNull() {
+		resp.Diagnostics.AddError("Missing kubernetes configuration", "k8s configuration is required unless certificate_url is set")
+		return
 	}
 
-	c, err := k8s.NewClient(&k8s.Config{
-		Host:          k8sCfg[host].(string),
-		ClusterCACert: []byte(k8sCfg[clusterCaCertificate].(string)),
-		ClientCert:    []byte(k8sCfg[clientCertificate].(string)),
-		ClientKey:     []byte(k8sCfg[clientKey].(string)),
-		Token:         k8sCfg[token].(string),
-	})
-	if err != nil {
-		return nil, diag.FromErr(err)
+	var c *k8s.Client
+	var err error
+	if len(cfg.Kubernetes) > 0 {
+		k8sCfg, diags := kubernetesConfig(ctx, cfg.Kubernetes[0])
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		if c, err = k8s.NewClient(k8sCfg); err != nil {
+			resp.Diagnostics.AddError("Unable to create the Kubernetes client", err.Error())
+			return
+		}
 	}
 
-	cName := rd.Get(controllerName).(string)
-	cNs := rd.Get(controllerNamespace).(string)
+	cName := stringOrDefault(cfg.ControllerName, kubeseal.DefaultControllerName)
+	cNs := stringOrDefault(cfg.ControllerNamespace, kubeseal.DefaultControllerNamespace)
 
-	return &ProviderConfig{
+	policy := kubeseal.ValidityPolicy{RejectExpired: cfg.RejectExpiredCert.ValueBool()}
+	if !cfg.MinCertValidity.IsNull() {
+		if policy.MinValidity, err = time.ParseDuration(cfg.MinCertValidity.ValueString()); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root(minCertValidity), "Invalid duration", err.Error())
+			return
+		}
+	}
+
+	var certs kubeseal.CertsResolverFunc
+	switch {
+	case cfg.CertificateURL.IsNull() && cfg.CertificateSource.ValueString() == sourceSecret:
+		certs = kubeseal.FetchCertsFromSecrets(c, cNs)
+	case cfg.CertificateURL.IsNull():
+		certs = kubeseal.FetchCerts(c, cName, cNs)
+	default:
+		src := kubeseal.CertURL{
+			URL:        cfg.CertificateURL.ValueString(),
+			CACert:     []byte(cfg.CertificateURLCA.ValueString()),
+			ClientCert: []byte(cfg.CertificateURLCert.ValueString()),
+			ClientKey:  []byte(cfg.CertificateURLKey.ValueString()),
+			AllowHTTP:  cfg.CertificateURLHTTP.ValueBool(),
+		}
+		if dir, err := os.UserCacheDir(); err == nil {
+			src.CacheDir = filepath.Join(dir, cacheDirName)
+		}
+		if certs, err = kubeseal.FetchCertsFromURL(src); err != nil {
+			detail := err.Error()
+			if errors.Is(err, kubeseal.ErrPlainHTTP) {
+				detail += ". Use an https:// URL, or set certificate_url_allow_http to accept it."
+			}
+			if errors.Is(err, kubeseal.ErrTLSWithoutHTTPS) {
+				detail += ". Use an https:// URL, or remove certificate_url_ca_certificate, certificate_url_client_certificate and certificate_url_client_key."
+			}
+			resp.Diagnostics.AddAttributeError(path.Root(certificateURL), "Invalid certificate URL", detail)
+			return
+		}
+	}
+	providerCfg := &ProviderConfig{
 		ControllerName:      cName,
 		ControllerNamespace: cNs,
-		PublicKeyResolver:   kubeseal.FetchPK(c, cName, cNs),
-	}, nil
+		MultipleControllers: cfg.MultipleControllers.ValueBool(),
+		PublicKeyResolver:   kubeseal.SelectPK(certs, cfg.CertificateFpr.ValueString(), policy),
+		Certificates:        certs,
+		HMACKey:             []byte(stringOrEnv(cfg.HMACKey, "SEALEDSECRET_HMAC_KEY")),
+		ResealOnKeyRotation: cfg.ResealOnKeyRotation.IsNull() || cfg.ResealOnKeyRotation.ValueBool(),
+		targets:             &secretTargets{},
+		stale:               &staleResources{settle: rotationSettleTime},
+	}
+	if c != nil {
+		providerCfg.SealedSecrets = c
+	}
+	resp.ResourceData = providerCfg
+	resp.EphemeralResourceData = providerCfg
+	resp.DataSourceData = providerCfg
+}
+
+func (p *sealedSecretProvider) Resources(ctx context.Context) []func() resource.Resource {
+	return []func() resource.Resource{
+		newSealedSecretResource,
+		newSealedSecretInClusterResource,
+		newSealedSecretBundleResource,
+		newSealedSecretMergeResource,
+	}
+}
+
+func (p *sealedSecretProvider) EphemeralResources(ctx context.Context) []func() ephemeral.EphemeralResource {
+	return []func() ephemeral.EphemeralResource{
+		newSealedSecretEphemeralResource,
+	}
+}
+
+func (p *sealedSecretProvider) Functions(ctx context.Context) []func() function.Function {
+	return []func() function.Function{
+		newSealFunction,
+		newSealManifestFunction,
+	}
+}
+
+func (p *sealedSecretProvider) DataSources(ctx context.Context) []func() datasource.DataSource {
+	return []func() datasource.DataSource{
+		newCertificatesDataSource,
+	}
+}
+
+// kubernetesConfig maps the kubernetes block onto the client configuration. The environment variables are only
+// used when neither the attribute nor its _file variant is set.
+func kubernetesConfig(ctx context.Context, m kubernetesModel) (*k8s.Config, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	cfg := &k8s.Config{
+		Host:              m.Host.ValueString(),
+		ClusterCACert:     []byte(m.ClusterCaCertificate.ValueString()),
+		ClusterCACertFile: m.ClusterCaCertFile.ValueString(),
+		ClientCertFile:    m.ClientCertFile.ValueString(),
+		ClientKeyFile:     m.ClientKeyFile.ValueString(),
+		TokenFile:         m.TokenFile.ValueString(),
+		ProxyURL:          m.ProxyURL.ValueString(),
+		TLSServerName:     m.TLSServerName.ValueString(),
+		Insecure:          m.Insecure.ValueBool(),
+		ImpersonateUser:   m.ImpersonateUser.ValueString(),
+		PortForward:       m.ConnectionMode.ValueString() == modePortForward,
+	}
+	if !m.ImpersonateGroups.IsNull() {
+		diags.Append(m.ImpersonateGroups.ElementsAs(ctx, &cfg.ImpersonateGroups, false)...)
+	}
+	if !m.ImpersonateExtra.IsNull() {
+		diags.Append(m.ImpersonateExtra.ElementsAs(ctx, &cfg.ImpersonateExtra, false)...)
+	}
+	if cfg.ClientCertFile == "" {
+		cfg.ClientCert = []byte(stringOrEnv(m.ClientCertificate, "KUBE_CLIENT_CERT_DATA"))
+	}
+	if cfg.ClientKeyFile == "" {
+		cfg.ClientKey = []byte(stringOrEnv(m.ClientKey, "KUBE_CLIENT_KEY_DATA"))
+	}
+	if cfg.TokenFile == "" {
+		cfg.Token = stringOrEnv(m.Token, "KUBE_TOKEN")
+	}
+	if !m.RequestTimeout.IsNull() {
+		timeout, err := time.ParseDuration(m.RequestTimeout.ValueString())
+		if err != nil {
+			diags.AddAttributeError(path.Root(kubernetes).AtListIndex(0).AtName(requestTimeout), "Invalid duration", err.Error())
+		}
+		cfg.Timeout = timeout
+	}
+	switch {
+	case m.InCluster.ValueBool():
+		if err := k8s.InCluster(cfg); err != nil {
+			diags.AddAttributeError(path.Root(kubernetes).AtListIndex(0).AtName(inCluster), "Unable to use the in-cluster configuration", err.Error())
+		}
+	case cfg.Host == "":
+		diags.AddAttributeError(path.Root(kubernetes).AtListIndex(0).AtName(host), "Missing host", "host is required unless in_cluster is set")
+	}
+	return cfg, diags
+}
+
+func stringOrEnv(v types.String, env string) string {
+	if v.IsNull() || v.IsUnknown() {
+		return os.Getenv(env)
+	}
+	return v.ValueString()
 }
 
-func getMapFromSchemaSet(rd *schema.ResourceData, key string) (map[string]interface{}, bool) {
-	m, ok := rd.GetOk(key)
-	if !ok {
-		return nil, ok
+func stringOrDefault(v types.String, def string) string {
+	if v.IsNull() || v.IsUnknown() || v.ValueString() == "" {
+		return def
 	}
-	return m.([]interface{})[0].(map[string]interface{}), ok
+	return v.ValueString()
 }
@@ -0,0 +1,143 @@
+package provider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/jifwin/terraform-provider-sealedsecret/internal/k8s"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKubernetesConfig(t *testing.T) {
+	t.Setenv("KUBE_TOKEN", "env_token_aaa")
+	t.Setenv("KUBE_CLIENT_CERT_DATA", "env_cert_aaa")
+	t.Setenv("KUBE_CLIENT_KEY_DATA", "env_key_aaa")
+
+	tests := []struct {
+		name     string
+		model    kubernetesModel
+		expected k8s.Config
+	}{
+		{
+			name:  "environment",
+			model: kubernetesModel{Host: types.StringValue("https://host_aaa"), ClusterCaCertificate: types.StringValue("ca_aaa")},
+			expected: k8s.Config{
+				Host:          "https://host_aaa",
+				ClusterCACert: []byte("ca_aaa"),
+				ClientCert:    []byte("env_cert_aaa"),
+				ClientKey:     []byte("env_key_aaa"),
+				Token:         "env_token_aaa",
+			},
+		},
+		{
+			name: "files win over the environment",
+			model: kubernetesModel{
+				Host:              types.StringValue("https://host_aaa"),
+				ClusterCaCertFile: types.StringValue("/ca_aaa"),
+				ClientCertFile:    types.StringValue("/cert_aaa"),
+				ClientKeyFile:     types.StringValue("/key_aaa"),
+				TokenFile:         types.StringValue("/token_aaa"),
+			},
+			expected: k8s.Config{
+				Host:              "https://host_aaa",
+				ClusterCACert:     []byte{},
+				ClusterCACertFile: "/ca_aaa",
+				ClientCertFile:    "/cert_aaa",
+				ClientKeyFile:     "/key_aaa",
+				TokenFile:         "/token_aaa",
+			},
+		},
+		{
+			name: "transport and impersonation",
+			model: kubernetesModel{
+				Host:              types.StringValue("https://host_aaa"),
+				Token:             types.StringValue("token_aaa"),
+				ProxyURL:          types.StringValue("http://proxy_aaa:3128"),
+				TLSServerName:     types.StringValue("server_aaa"),
+				Insecure:          types.BoolValue(true),
+				RequestTimeout:    types.StringValue("30s"),
+				ImpersonateUser:   types.StringValue("user_aaa"),
+				ImpersonateGroups: types.ListValueMust(types.StringType, []attr.Value{types.StringValue("group_aaa")}),
+				ImpersonateExtra: types.MapValueMust(types.ListType{ElemType: types.StringType}, map[string]attr.Value{
+					"scopes": types.ListValueMust(types.StringType, []attr.Value{types.StringValue("scope_aaa")}),
+				}),
+			},
+			expected: k8s.Config{
+				Host:              "https://host_aaa",
+				ClusterCACert:     []byte{},
+				ClientCert:        []byte("env_cert_aaa"),
+				ClientKey:         []byte("env_key_aaa"),
+				Token:             "token_aaa",
+				ProxyURL:          "http://proxy_aaa:3128",
+				TLSServerName:     "server_aaa",
+				Insecure:          true,
+				Timeout:           30 * time.Second,
+				ImpersonateUser:   "user_aaa",
+				ImpersonateGroups: []string{"group_aaa"},
+				ImpersonateExtra:  map[string][]string{"scopes": {"scope_aaa"}},
+			},
+		},
+		{
+			name: "port-forward",
+			model: kubernetesModel{
+				Host:           types.StringValue("https://host_aaa"),
+				Token:          types.StringValue("token_aaa"),
+				ConnectionMode: types.StringValue("port-forward"),
+			},
+			expected: k8s.Config{
+				Host:          "https://host_aaa",
+				ClusterCACert: []byte{},
+				ClientCert:    []byte("env_cert_aaa"),
+				ClientKey:     []byte("env_key_aaa"),
+				Token:         "token_aaa",
+				PortForward:   true,
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, diags := kubernetesConfig(context.Background(), tc.model)
+			assert.False(t, diags.HasError())
+			assert.Equal(t, tc.expected, *cfg)
+		})
+	}
+}
+
+func TestKubernetesConfigInCluster(t *testing.T) {
+	t.Setenv("KUBERNETES_SERVICE_HOST", "10.0.0.1")
+	t.Setenv("KUBERNETES_SERVICE_PORT", "443")
+	tokenFile := k8s.InClusterTokenFile
+	t.Cleanup(func() { k8s.InClusterTokenFile = tokenFile })
+	k8s.InClusterTokenFile = filepath.Join(t.TempDir(), "token")
+	assert.Nil(t, os.WriteFile(k8s.InClusterTokenFile, []byte("sa_token_aaa"), 0o600))
+
+	cfg, diags := kubernetesConfig(context.Background(), kubernetesModel{InCluster: types.BoolValue(true)})
+	assert.False(t, diags.HasError())
+	assert.Equal(t, "https://10.0.0.1:443", cfg.Host)
+	assert.Equal(t, k8s.InClusterTokenFile, cfg.TokenFile)
+	assert.Equal(t, k8s.InClusterCACertFile, cfg.ClusterCACertFile)
+
+	// the attributes override the pod's settings
+	cfg, diags = kubernetesConfig(context.Background(), kubernetesModel{
+		InCluster:         types.BoolValue(true),
+		Host:              types.StringValue("https://host_aaa"),
+		ClusterCaCertFile: types.StringValue("/ca_aaa"),
+	})
+	assert.False(t, diags.HasError())
+	assert.Equal(t, "https://host_aaa", cfg.Host)
+	assert.Equal(t, "/ca_aaa", cfg.ClusterCACertFile)
+
+	_, diags = kubernetesConfig(context.Background(), kubernetesModel{})
+	assert.True(t, diags.HasError())
+	assert.Equal(t, "host is required unless in_cluster is set", diags[0].Detail())
+
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+	_, diags = kubernetesConfig(context.Background(), kubernetesModel{InCluster: types.BoolValue(true)})
+	assert.True(t, diags.HasError())
+	assert.Equal(t, k8s.ErrNotInCluster.Error(), diags[0].Detail())
+}
@@ -3,26 +3,62 @@ package provider
 import (
 	"context"
 	"crypto/rsa"
+	"errors"
 	"fmt"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
-	"github.com/jifwin/terraform-provider-sealedsecret/internal/k8s"
-	"github.com/jifwin/terraform-provider-sealedsecret/internal/kubeseal"
-	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
-	"log"
 	"strconv"
 	"strings"
 	"time"
+
+	ssv1alpha1 "github.com/bitnami-labs/sealed-secrets/pkg/apis/sealedsecrets/v1alpha1"
+	"github.com/hashicorp/terraform-plugin-framework-validators/resourcevalidator"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/boolplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/mapplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/jifwin/terraform-provider-sealedsecret/internal/k8s"
+	"github.com/jifwin/terraform-provider-sealedsecret/internal/kubeseal"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/wait"
 )
 
 const (
-	name         = "name"
-	namespace    = "namespace"
-	secretType   = "type"
-	data         = "data"
-	yaml_content = "yaml_content"
-	public_key   = "public_key"
+	name            = "name"
+	namespace       = "namespace"
+	secretType      = "type"
+	data            = "data"
+	dataWO          = "data_wo"
+	dataWOVersion   = "data_wo_version"
+	yaml_content    = "yaml_content"
+	public_key      = "public_key"
+	hashData        = "hash_data"
+	dataHmac        = "data_hmac"
+	nameSuffixHash  = "name_suffix_hash"
+	secretName      = "secret_name"
+	encryptedData   = "encrypted_data"
+	helmValuesKey   = "helm_values_key"
+	helmValuesYaml  = "helm_values_yaml"
+	dataFiles       = "data_files"
+	dataFromDir     = "data_from_directory"
+	dataFilesSha256 = "data_files_sha256"
+	dataFromEnvFile = "data_from_env_file"
+	dataFromJSON    = "data_from_json"
+	dataFromYAML    = "data_from_yaml"
+	sealedWithFpr   = "sealed_with_fingerprint"
+	needsReseal     = "needs_reseal"
+	id              = "id"
+	defaultType     = "Opaque"
+	pkFetchTimeout  = 3 * time.Minute
+	pkFetchInterval = 5 * time.Second
 )
 
 type SealedSecret struct {
@@ -38,147 +74,877 @@ type SealedSecret struct {
 	} `yaml:"spec"`
 }
 
-func resourceLocal() *schema.Resource {
-	return &schema.Resource{
-		Description:   "Creates a sealed secret and store it in yaml_content.",
-		CreateContext: resourceCreate,
-		ReadContext:   resourceRead,
-		DeleteContext: resourceDelete,
-		Schema: map[string]*schema.Schema{
-			name: {
-				Type:        schema.TypeString,
-				Required:    true,
-				ForceNew:    true,
-				Description: "name of the secret, must be unique",
-			},
-			namespace: {
-				Type:        schema.TypeString,
-				Required:    true,
-				ForceNew:    true,
-				Description: "namespace of the secret",
-			},
-			secretType: {
-				Type:        schema.TypeString,
-				Optional:    true,
-				Default:     "Opaque",
-				ForceNew:    true,
-				Description: "The secret type (ex. Opaque). Default type is Opaque.",
+var (
+	_ resource.ResourceWithConfigure        = &sealedSecretResource{}
+	_ resource.ResourceWithConfigValidators = &sealedSecretResource{}
+	_ resource.ResourceWithValidateConfig   = &sealedSecretResource{}
+	_ resource.ResourceWithModifyPlan       = &sealedSecretResource{}
+	_ resource.ResourceWithUpgradeState     = &sealedSecretResource{}
+	_ resource.ResourceWithImportState      = &sealedSecretResource{}
+)
+
+type sealedSecretResource struct {
+	provider *ProviderConfig
+}
+
+type sealedSecretModel struct {
+	ID                types.String `tfsdk:"id"`
+	Name              types.String `tfsdk:"name"`
+	Namespace         types.String `tfsdk:"namespace"`
+	Type              types.String `tfsdk:"type"`
+	Data              types.Map    `tfsdk:"data"`
+	DataWO            types.Map    `tfsdk:"data_wo"`
+	DataWOVersion     types.Int64  `tfsdk:"data_wo_version"`
+	DataFiles         types.Map    `tfsdk:"data_files"`
+	DataFromDir       types.String `tfsdk:"data_from_directory"`
+	DataFilesSha256   types.Map    `tfsdk:"data_files_sha256"`
+	DataFromEnvFile   types.String `tfsdk:"data_from_env_file"`
+	DataFromJSON      types.String `tfsdk:"data_from_json"`
+	DataFromYAML      types.String `tfsdk:"data_from_yaml"`
+	HashData          types.Bool   `tfsdk:"hash_data"`
+	DataHmac          types.Map    `tfsdk:"data_hmac"`
+	NameSuffixHash    types.Bool   `tfsdk:"name_suffix_hash"`
+	SecretName        types.String `tfsdk:"secret_name"`
+	KustomizationYaml types.String `tfsdk:"kustomization_yaml"`
+	HelmValuesKey     types.String `tfsdk:"helm_values_key"`
+	HelmValuesYaml    types.String `tfsdk:"helm_values_yaml"`
+	EncryptedData     types.Map    `tfsdk:"encrypted_data"`
+	YamlContent       types.String `tfsdk:"yaml_content"`
+	PublicKey         types.String `tfsdk:"public_key"`
+	SealedWithFpr     types.String `tfsdk:"sealed_with_fingerprint"`
+	NeedsReseal       types.Bool   `tfsdk:"needs_reseal"`
+}
+
+func newSealedSecretResource() resource.Resource {
+	return &sealedSecretResource{}
+}
+
+func (r *sealedSecretResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName
+}
+
+func (r *sealedSecretResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Creates a sealed secret and store it in yaml_content.",
+		Version:     2,
+		Attributes: map[string]schema.Attribute{
+			id: schema.StringAttribute{
+				Computed: true,
+			},
+			name: schema.StringAttribute{
+				Required:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
+				Validators:    []validator.String{secretNameValidator{}},
+				Description:   "name of the secret, must be unique",
+			},
+			namespace: schema.StringAttribute{
+				Required:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
+				Validators:    []validator.String{namespaceValidator{}},
+				Description:   "namespace of the secret",
 			},
-			data: {
-				Type:        schema.TypeMap,
+			secretType: schema.StringAttribute{
+				Optional:      true,
+				Computed:      true,
+				Default:       stringdefault.StaticString(defaultType),
+				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
+				Description:   "The secret type (ex. Opaque). Default type is Opaque.",
+			},
+			data: schema.MapAttribute{
+				ElementType:   types.StringType,
+				Optional:      true,
+				Sensitive:     true,
+				PlanModifiers: []planmodifier.Map{mapplanmodifier.RequiresReplace()},
+				Validators:    []validator.Map{secretDataValidator{}},
+				Description:   "Key/value pairs to populate the secret. The value will be base64 encoded",
+			},
+			dataWO: schema.MapAttribute{
+				ElementType: types.StringType,
 				Optional:    true,
 				Sensitive:   true,
-				ForceNew:    true,
-				Description: "Key/value pairs to populate the secret. The value will be base64 encoded",
+				WriteOnly:   true,
+				Validators:  []validator.Map{secretDataValidator{}},
+				Description: "Write-only variant of data, the values are never stored in the state. " +
+					"Changes are detected through data_wo_version or, with hash_data, through data_hmac. Requires Terraform 1.11 or later.",
+			},
+			dataWOVersion: schema.Int64Attribute{
+				Optional:      true,
+				PlanModifiers: []planmodifier.Int64{int64planmodifier.RequiresReplace()},
+				Description:   "Bump this value to re-seal data_wo.",
 			},
-			yaml_content: {
-				Type:        schema.TypeString,
+			dataFiles: schema.MapAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Validators:  []validator.Map{secretDataValidator{keysOnly: true}},
+				Description: "Key/path pairs, the contents of the files populate the secret without being stored in the state.",
+			},
+			dataFromDir: schema.StringAttribute{
+				Optional:    true,
+				Description: "Populate the secret with every regular file of the directory, keyed by file name, like kubectl create secret --from-file.",
+			},
+			dataFromEnvFile: schema.StringAttribute{
+				Optional:    true,
+				Description: "Populate the secret with the KEY=VALUE lines of the file, like kubectl create secret --from-env-file. Only a HMAC of every value, keyed with the provider hmac_key, is stored.",
+			},
+			dataFilesSha256: schema.MapAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: "HMAC-SHA256 of every value read through data_files, data_from_directory and data_from_env_file, keyed with the provider hmac_key, the secret is re-sealed when it changes.",
+			},
+			dataFromJSON: schema.StringAttribute{
+				Optional:      true,
+				Sensitive:     true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
+				Description:   "A flat JSON object to populate the secret with, numbers and booleans are converted to strings.",
+			},
+			dataFromYAML: schema.StringAttribute{
+				Optional:      true,
+				Sensitive:     true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
+				Description:   "A flat YAML mapping to populate the secret with, numbers and booleans are converted to strings.",
+			},
+			hashData: schema.BoolAttribute{
+				Optional:      true,
+				Computed:      true,
+				Default:       booldefault.StaticBool(false),
+				PlanModifiers: []planmodifier.Bool{boolplanmodifier.RequiresReplace()},
+				Description:   "Store a HMAC of every data_wo value in data_hmac and re-seal when it changes. Requires the provider hmac_key.",
+			},
+			dataHmac: schema.MapAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: "HMAC-SHA256 of every data_wo entry, set when hash_data is enabled.",
+			},
+			nameSuffixHash: schema.BoolAttribute{
+				Optional:      true,
+				Computed:      true,
+				Default:       booldefault.StaticBool(false),
+				PlanModifiers: []planmodifier.Bool{boolplanmodifier.RequiresReplace()},
+				Description: "Append a hash of the secret to its name, like the Kustomize secretGenerator, so workloads roll when it changes. " +
+					"The hash is a HMAC keyed with the provider hmac_key and only changes when the data changes. " +
+					"The secret is sealed with the namespace-wide scope, as the name is not known before sealing.",
+			},
+			secretName: schema.StringAttribute{
+				Computed:    true,
+				Description: "The name of the Secret, including the hash suffix when name_suffix_hash is enabled.",
+			},
+			kustomizationYaml: schema.StringAttribute{
+				Computed:    true,
+				Description: "A kustomization.yaml fragment listing yaml_content as <secret_name>.yaml.",
+			},
+			helmValuesKey: schema.StringAttribute{
+				Optional:    true,
+				Validators:  []validator.String{helmValuesKeyValidator{}},
+				Description: "Dot separated path, e.g. sealedSecret.encryptedData, to render encrypted_data under in helm_values_yaml.",
+			},
+			helmValuesYaml: schema.StringAttribute{
+				Computed:    true,
+				Description: "encrypted_data as Helm values nested under helm_values_key, set when helm_values_key is.",
+			},
+			encryptedData: schema.MapAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: "The ciphertext of every key, as found in spec.encryptedData of yaml_content.",
+			},
+			yaml_content: schema.StringAttribute{
 				Computed:    true,
 				Description: "The produced sealed secret yaml file.",
 			},
-			public_key: {
-				Type:        schema.TypeString,
+			public_key: schema.StringAttribute{
 				Computed:    true,
 				Description: "The key used for encryption",
 			},
+			sealedWithFpr: schema.StringAttribute{
+				Computed:    true,
+				Description: "SHA256 fingerprint of the controller key the secret was sealed with.",
+			},
+			needsReseal: schema.BoolAttribute{
+				Computed:    true,
+				Description: "Whether the controller seals with a newer key than sealed_with_fingerprint. Only stays true when the provider's reseal_on_key_rotation is false.",
+			},
 		},
 	}
 }
 
-func resourceRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	provider := meta.(*ProviderConfig)
-	pk, err := getPublicKey(ctx, provider)
-	if err != nil {
-		return diag.FromErr(err)
+func (r *sealedSecretResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+	r.provider = req.ProviderData.(*ProviderConfig)
+}
+
+func (r *sealedSecretResource) ConfigValidators(ctx context.Context) []resource.ConfigValidator {
+	return []resource.ConfigValidator{
+		resourcevalidator.Conflicting(path.MatchRoot(data), path.MatchRoot(dataWO)),
+		resourcevalidator.Conflicting(path.MatchRoot(data), path.MatchRoot(dataWOVersion)),
 	}
+}
 
-	if d.HasChanges(name, namespace, secretType, data) || (formatPublicKeyAsString(pk) != d.Get("public_key")) {
-		return resourceCreate(ctx, d, meta)
+func (r *sealedSecretResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var cfg sealedSecretModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &cfg)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if cfg.HashData.ValueBool() && !cfg.Data.IsNull() {
+		resp.Diagnostics.AddAttributeError(path.Root(data), "data is stored in the state",
+			"hash_data only keeps the values out of the state when they are passed through data_wo.")
+	}
+	if !cfg.DataWO.IsNull() && !cfg.HashData.ValueBool() && cfg.DataWOVersion.IsNull() {
+		resp.Diagnostics.AddAttributeWarning(path.Root(dataWO), "Changes to data_wo are not detected",
+			"Set data_wo_version or hash_data so the secret is re-sealed when data_wo changes.")
+	}
+	if cfg.NameSuffixHash.ValueBool() && !cfg.Name.IsNull() && !cfg.Name.IsUnknown() {
+		if err := k8s.ValidateHashedName(cfg.Name.ValueString()); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root(name), "Invalid secret name", err.Error())
+		}
+	}
+	if !cfg.DataFromJSON.IsNull() && !cfg.DataFromJSON.IsUnknown() {
+		if _, err := k8s.ParseJSONData([]byte(cfg.DataFromJSON.ValueString())); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root(dataFromJSON), "Invalid data_from_json", err.Error())
+		}
+	}
+	if !cfg.DataFromYAML.IsNull() && !cfg.DataFromYAML.IsUnknown() {
+		if _, err := k8s.ParseYAMLData([]byte(cfg.DataFromYAML.ValueString())); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root(dataFromYAML), "Invalid data_from_yaml", err.Error())
+		}
+	}
+}
+
+func (r *sealedSecretResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() || r.provider == nil {
+		return
+	}
+
+	var plan, state, cfg sealedSecretModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.Config.Get(ctx, &cfg)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx = withLogging(ctx, secretFields("sealedsecret", plan.Namespace.ValueString(), plan.Name.ValueString()))
+	if plan.NameSuffixHash.ValueBool() && len(r.provider.HMACKey) == 0 {
+		resp.Diagnostics.AddAttributeError(path.Root(nameSuffixHash), "Unable to hash the secret name", errMissingHMACKey.Error())
+		return
+	}
+
+	// files are read at plan time, so changed contents show up as a replacement
+	plan.DataFilesSha256 = types.MapUnknown(types.StringType)
+	if !plan.DataFiles.IsUnknown() && !plan.DataFromDir.IsUnknown() && !plan.DataFromEnvFile.IsUnknown() {
+		files, diags := readFiles(ctx, plan)
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		plan.DataFilesSha256 = filesSha256(ctx, r.provider.HMACKey, files, &resp.Diagnostics)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		resp.Diagnostics.Append(checkDataKeys(ctx, cfg, files)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+	plan.ID = secretID(r.provider, plan.Namespace, plan.Name)
+	if !plan.NameSuffixHash.ValueBool() {
+		r.provider.targets.claim(&resp.Diagnostics, sealingClaim, plan.Namespace.ValueString(), plan.Name.ValueString(),
+			"a sealedsecret resource", claimToken(ctx, resp.Private, &resp.Diagnostics))
+	}
+	if req.State.Raw.IsNull() {
+		resp.Diagnostics.Append(checkCertificateExpiry(ctx, r.provider)...)
+		resp.Diagnostics.Append(resp.Plan.Set(ctx, &plan)...)
+		return
+	}
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var replace path.Paths
+	if !plan.DataFilesSha256.Equal(state.DataFilesSha256) {
+		replace = append(replace, path.Root(dataFilesSha256))
+	}
+	rotation, diags := checkKeyRotation(ctx, r.provider, state.PublicKey)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if rotation.stale {
+		addKeyRotationWarning(ctx, &resp.Diagnostics, r.provider, "sealedsecret "+state.ID.ValueString(), rotation)
+		if r.provider.ResealOnKeyRotation {
+			replace = append(replace, path.Root(public_key))
+		}
+	}
+
+	if plan.HashData.ValueBool() {
+		if cfg.DataWO.IsUnknown() {
+			plan.DataHmac = types.MapUnknown(types.StringType)
+			replace = append(replace, path.Root(dataHmac))
+		} else {
+			hashes, diags := hashDataWO(ctx, r.provider.HMACKey, cfg.DataWO)
+			resp.Diagnostics.Append(diags...)
+			if resp.Diagnostics.HasError() {
+				return
+			}
+			plan.DataHmac = hashes
+			if !hashes.Equal(state.DataHmac) {
+				replace = append(replace, path.Root(dataHmac))
+			}
+		}
+	}
+
+	if len(replace) > 0 {
+		plan.SecretName = types.StringUnknown()
+		plan.KustomizationYaml = types.StringUnknown()
+		plan.HelmValuesYaml = types.StringUnknown()
+		plan.EncryptedData = types.MapUnknown(types.StringType)
+		plan.YamlContent = types.StringUnknown()
+		plan.PublicKey = types.StringUnknown()
+		plan.SealedWithFpr = types.StringUnknown()
+		plan.NeedsReseal = types.BoolValue(false)
+		resp.RequiresReplace = append(resp.RequiresReplace, replace...)
 	} else {
-		return nil
+		// Only outputs like helm_values_yaml, and IDs of older versions, can change in place, the sealed secret is kept.
+		plan.SecretName = state.SecretName
+		plan.KustomizationYaml = state.KustomizationYaml
+		plan.EncryptedData = state.EncryptedData
+		plan.YamlContent = state.YamlContent
+		plan.PublicKey = state.PublicKey
+		plan.SealedWithFpr = state.SealedWithFpr
+		plan.NeedsReseal = types.BoolValue(rotation.stale)
+		if !plan.HashData.ValueBool() {
+			plan.DataHmac = state.DataHmac
+		}
+		resp.Diagnostics.Append(r.setHelmValues(ctx, &plan)...)
 	}
+	resp.Diagnostics.Append(resp.Plan.Set(ctx, &plan)...)
 }
-func resourceCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	provider := meta.(*ProviderConfig)
-	filePath := d.Get(name).(string)
-	pk, err := getPublicKey(ctx, provider)
+
+func (r *sealedSecretResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan, cfg sealedSecretModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.Config.Get(ctx, &cfg)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	secretData := plan.Data
+	if !cfg.DataWO.IsNull() {
+		secretData = cfg.DataWO
+	}
+	manifest := k8s.SecretManifest{
+		Name:      plan.Name.ValueString(),
+		Namespace: plan.Namespace.ValueString(),
+		Type:      plan.Type.ValueString(),
+	}
+	resp.Diagnostics.Append(secretData.ElementsAs(ctx, &manifest.Data, false)...)
+	if manifest.Data == nil {
+		manifest.Data = make(map[string]string)
+	}
+	files, diags := readFiles(ctx, plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	plan.DataFilesSha256 = filesSha256(ctx, r.provider.HMACKey, files, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if err := addDocumentData(manifest.Data, files, plan); err != nil {
+		resp.Diagnostics.AddError("Unable to collect the secret data", err.Error())
+		return
+	}
+	ctx = withLogging(ctx, secretFields("sealedsecret", manifest.Namespace, manifest.Name))
+	ctx = maskSecretValues(ctx, manifest.Data)
+
+	scope := ssv1alpha1.StrictScope
+	if plan.NameSuffixHash.ValueBool() {
+		hashed, err := k8s.HashedName(&manifest, r.provider.HMACKey)
+		if err != nil {
+			resp.Diagnostics.AddError("Unable to hash the secret", err.Error())
+			return
+		}
+		manifest.Name = hashed
+		scope = ssv1alpha1.NamespaceWideScope
+	}
+	kustomizationContent, err := kustomizationFor([]string{manifest.Name + ".yaml"})
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to create the kustomization", err.Error())
+		return
+	}
+
+	tflog.Debug(ctx, "Sealing the secret", map[string]interface{}{"secret_name": manifest.Name, "scope": scope.String()})
+	sealedSecret, pk, err := createSealedSecret(ctx, r.provider, &manifest, scope)
 	if err != nil {
-		return diag.FromErr(err)
+		resp.Diagnostics.AddError("Unable to create the sealed secret", err.Error())
+		return
 	}
+	tflog.Debug(ctx, "Sealed the secret")
 
-	logDebug("Creating sealed secret for path " + filePath)
-	sealedSecret, err := createSealedSecret(ctx, provider, d)
+	plan.ID = secretID(r.provider, plan.Namespace, plan.Name)
+	plan.SecretName = types.StringValue(manifest.Name)
+	plan.KustomizationYaml = types.StringValue(kustomizationContent)
+	encrypted, err := encryptedDataOf(sealedSecret)
 	if err != nil {
-		return diag.FromErr(err)
+		resp.Diagnostics.AddError("Unable to read the sealed secret", err.Error())
+		return
 	}
-	logDebug("Successfully created sealed secret for path " + filePath)
+	encryptedValue, diags := types.MapValueFrom(ctx, types.StringType, encrypted)
+	resp.Diagnostics.Append(diags...)
+	plan.EncryptedData = encryptedValue
+	resp.Diagnostics.Append(r.setHelmValues(ctx, &plan)...)
+	plan.YamlContent = types.StringValue(string(sealedSecret))
+	plan.PublicKey = types.StringValue(formatPublicKeyAsString(pk))
+	plan.SealedWithFpr = types.StringValue(kubeseal.Fingerprint(pk))
+	plan.NeedsReseal = types.BoolValue(false)
+	plan.DataWO = types.MapNull(types.StringType)
+	plan.DataHmac = types.MapNull(types.StringType)
+	if plan.HashData.ValueBool() {
+		hashes, diags := hashDataWO(ctx, r.provider.HMACKey, cfg.DataWO)
+		resp.Diagnostics.Append(diags...)
+		plan.DataHmac = hashes
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
 
-	d.SetId(filePath)
-	d.Set(data, d.Get(data).(map[string]interface{})) //TODO: update
-	d.Set("yaml_content", string(sealedSecret))
-	d.Set("public_key", formatPublicKeyAsString(pk))
+func (r *sealedSecretResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	// Nothing is stored remotely, public key changes are detected in ModifyPlan.
+	// Resources created by older versions get the attributes added since, so they are not replaced.
+	var state sealedSecretModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if state.NameSuffixHash.IsNull() {
+		state.NameSuffixHash = types.BoolValue(false)
+		state.SecretName = state.Name
+		kustomizationContent, err := kustomizationFor([]string{state.Name.ValueString() + ".yaml"})
+		if err != nil {
+			resp.Diagnostics.AddError("Unable to create the kustomization", err.Error())
+			return
+		}
+		state.KustomizationYaml = types.StringValue(kustomizationContent)
+	}
+	if state.SealedWithFpr.IsNull() {
+		fingerprint, err := storedKeyFingerprint(state.PublicKey.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddError("Unable to read the public key", err.Error())
+			return
+		}
+		state.SealedWithFpr = types.StringValue(fingerprint)
+		state.NeedsReseal = types.BoolValue(false)
+	}
+	if state.EncryptedData.IsNull() {
+		encrypted, err := encryptedDataOf([]byte(state.YamlContent.ValueString()))
+		if err != nil {
+			resp.Diagnostics.AddError("Unable to read the sealed secret", err.Error())
+			return
+		}
+		encryptedValue, diags := types.MapValueFrom(ctx, types.StringType, encrypted)
+		resp.Diagnostics.Append(diags...)
+		state.EncryptedData = encryptedValue
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
 
-	return nil
+func (r *sealedSecretResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan sealedSecretModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *sealedSecretResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+}
+
+// ImportState reads the SealedSecret <namespace>/<name> from the cluster. Its plaintext cannot be read back, so the
+// data of the configuration is sealed again by the next apply.
+func (r *sealedSecretResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	ns, n, ok := strings.Cut(req.ID, "/")
+	// the controller appended to the id with multiple_controllers is the one of the provider configuration
+	n, _, _ = strings.Cut(n, "@")
+	if !ok || ns == "" || n == "" {
+		resp.Diagnostics.AddError("Invalid import ID", fmt.Sprintf("expected <namespace>/<name>, got %q", req.ID))
+		return
+	}
+	if r.provider == nil || r.provider.SealedSecrets == nil {
+		resp.Diagnostics.AddError("Missing kubernetes configuration", "the SealedSecret is read from the cluster, the provider needs its kubernetes block to import it")
+		return
+	}
+	ctx = withLogging(ctx, secretFields("sealedsecret", ns, n))
+
+	live, err := r.provider.SealedSecrets.GetSealedSecret(ctx, ns, n)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to read the SealedSecret", err.Error())
+		return
+	}
+	manifest, err := k8s.EncodeSealedSecret(live)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to encode the SealedSecret", err.Error())
+		return
+	}
+	secretTypeValue, _, _ := unstructured.NestedString(live.Object, "spec", "template", "type")
+	if secretTypeValue == "" {
+		secretTypeValue = defaultType
+	}
+	// the key that sealed it is unknown, the current one is assumed
+	pk, diags := planPublicKey(ctx, r.provider)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	state := sealedSecretModel{
+		ID:                secretID(r.provider, types.StringValue(ns), types.StringValue(n)),
+		Name:              types.StringValue(n),
+		Namespace:         types.StringValue(ns),
+		Type:              types.StringValue(secretTypeValue),
+		Data:              types.MapNull(types.StringType),
+		DataWO:            types.MapNull(types.StringType),
+		DataWOVersion:     types.Int64Null(),
+		DataFiles:         types.MapNull(types.StringType),
+		DataFromDir:       types.StringNull(),
+		DataFilesSha256:   types.MapNull(types.StringType),
+		DataFromEnvFile:   types.StringNull(),
+		DataFromJSON:      types.StringNull(),
+		DataFromYAML:      types.StringNull(),
+		HashData:          types.BoolValue(false),
+		DataHmac:          types.MapNull(types.StringType),
+		NameSuffixHash:    types.BoolNull(),
+		SecretName:        types.StringNull(),
+		KustomizationYaml: types.StringNull(),
+		HelmValuesKey:     types.StringNull(),
+		HelmValuesYaml:    types.StringNull(),
+		EncryptedData:     types.MapNull(types.StringType),
+		YamlContent:       types.StringValue(string(manifest)),
+		PublicKey:         types.StringValue(formatPublicKeyAsString(pk)),
+		SealedWithFpr:     types.StringNull(),
+		NeedsReseal:       types.BoolNull(),
+	}
+	// Read fills in the attributes derived from yaml_content and public_key
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
+
+func (r *sealedSecretResource) UpgradeState(ctx context.Context) map[int64]resource.StateUpgrader {
+	// version 1 only differs in the id, attributes added since are null in older states
+	var v1 resource.SchemaResponse
+	r.Schema(ctx, resource.SchemaRequest{}, &v1)
+
+	return map[int64]resource.StateUpgrader{
+		// version 0 was written by the terraform-plugin-sdk implementation
+		0: {
+			PriorSchema: &schema.Schema{
+				Attributes: map[string]schema.Attribute{
+					id:           schema.StringAttribute{Computed: true},
+					name:         schema.StringAttribute{Required: true},
+					namespace:    schema.StringAttribute{Required: true},
+					secretType:   schema.StringAttribute{Optional: true},
+					data:         schema.MapAttribute{ElementType: types.StringType, Optional: true, Sensitive: true},
+					hashData:     schema.BoolAttribute{Optional: true},
+					dataHmac:     schema.MapAttribute{ElementType: types.StringType, Computed: true},
+					yaml_content: schema.StringAttribute{Computed: true},
+					public_key:   schema.StringAttribute{Computed: true},
+				},
+			},
+			StateUpgrader: upgradeStateV0,
+		},
+		1: {
+			PriorSchema:   &v1.Schema,
+			StateUpgrader: upgradeStateV1,
+		},
+	}
+}
+
+// upgradeStateV1 replaces the name used as id by versions before 2 with <namespace>/<name>. The controller is added
+// by the next plan when it is not the default.
+func upgradeStateV1(ctx context.Context, req resource.UpgradeStateRequest, resp *resource.UpgradeStateResponse) {
+	var prior sealedSecretModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &prior)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	prior.ID = secretID(nil, prior.Namespace, prior.Name)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &prior)...)
+}
+
+type sealedSecretModelV0 struct {
+	ID          types.String `tfsdk:"id"`
+	Name        types.String `tfsdk:"name"`
+	Namespace   types.String `tfsdk:"namespace"`
+	Type        types.String `tfsdk:"type"`
+	Data        types.Map    `tfsdk:"data"`
+	HashData    types.Bool   `tfsdk:"hash_data"`
+	DataHmac    types.Map    `tfsdk:"data_hmac"`
+	YamlContent types.String `tfsdk:"yaml_content"`
+	PublicKey   types.String `tfsdk:"public_key"`
+}
+
+func upgradeStateV0(ctx context.Context, req resource.UpgradeStateRequest, resp *resource.UpgradeStateResponse) {
+	var prior sealedSecretModelV0
+	resp.Diagnostics.Append(req.State.Get(ctx, &prior)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	upgraded := sealedSecretModel{
+		ID:                secretID(nil, prior.Namespace, prior.Name),
+		Name:              prior.Name,
+		Namespace:         prior.Namespace,
+		Type:              prior.Type,
+		Data:              prior.Data,
+		DataWO:            types.MapNull(types.StringType),
+		DataWOVersion:     types.Int64Null(),
+		HashData:          types.BoolValue(prior.HashData.ValueBool()),
+		DataHmac:          prior.DataHmac,
+		YamlContent:       prior.YamlContent,
+		PublicKey:         prior.PublicKey,
+		NameSuffixHash:    types.BoolNull(),
+		SecretName:        types.StringNull(),
+		KustomizationYaml: types.StringNull(),
+		HelmValuesKey:     types.StringNull(),
+		HelmValuesYaml:    types.StringNull(),
+		EncryptedData:     types.MapNull(types.StringType),
+		DataFiles:         types.MapNull(types.StringType),
+		DataFromDir:       types.StringNull(),
+		DataFilesSha256:   types.MapNull(types.StringType),
+		DataFromEnvFile:   types.StringNull(),
+		DataFromJSON:      types.StringNull(),
+		DataFromYAML:      types.StringNull(),
+	}
+	if upgraded.Type.IsNull() {
+		upgraded.Type = types.StringValue(defaultType)
+	}
+	if upgraded.DataHmac.IsNull() || !upgraded.HashData.ValueBool() {
+		upgraded.DataHmac = types.MapNull(types.StringType)
+	}
+	if upgraded.HashData.ValueBool() {
+		// the sdk implementation dropped data from the state, it is passed through data_wo now
+		upgraded.Data = types.MapNull(types.StringType)
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &upgraded)...)
+}
+
+// readFiles reads data_files and data_from_directory, the result is nil when neither is set.
+func readFiles(ctx context.Context, m sealedSecretModel) (map[string]string, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	if m.DataFiles.IsNull() && m.DataFromDir.IsNull() && m.DataFromEnvFile.IsNull() {
+		return nil, diags
+	}
+	paths := make(map[string]string)
+	diags.Append(m.DataFiles.ElementsAs(ctx, &paths, false)...)
+	if diags.HasError() {
+		return nil, diags
+	}
+	files, err := readDataFiles(paths, m.DataFromDir.ValueString(), m.DataFromEnvFile.ValueString())
+	if err != nil {
+		diags.AddAttributeError(path.Root(dataFiles), "Unable to read the data files", err.Error())
+	}
+	return files, diags
 }
 
-func resourceDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	d.SetId("")
+// checkDataKeys reports keys set by more than one source and secrets over the size limit at plan time, when all
+// sources are known.
+func checkDataKeys(ctx context.Context, cfg sealedSecretModel, files map[string]string) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if cfg.Data.IsUnknown() || cfg.DataWO.IsUnknown() || cfg.DataFromJSON.IsUnknown() || cfg.DataFromYAML.IsUnknown() {
+		return diags
+	}
+	secretData := cfg.Data
+	if !cfg.DataWO.IsNull() {
+		secretData = cfg.DataWO
+	}
+	values := make(map[string]string)
+	diags.Append(secretData.ElementsAs(ctx, &values, false)...)
+	if diags.HasError() {
+		return diags
+	}
+	if values == nil {
+		values = make(map[string]string)
+	}
+	if err := addDocumentData(values, files, cfg); err != nil {
+		diags.AddError("Conflicting secret data", err.Error())
+		return diags
+	}
+	if err := k8s.ValidateSize(values); err != nil {
+		diags.AddError("Secret too large", err.Error())
+	}
+	return diags
+}
+
+// addDocumentData adds the values read from files and parsed from data_from_json and data_from_yaml to data.
+func addDocumentData(data, files map[string]string, m sealedSecretModel) error {
+	if err := addData(data, files, "a file"); err != nil {
+		return err
+	}
+	if !m.DataFromJSON.IsNull() {
+		values, err := k8s.ParseJSONData([]byte(m.DataFromJSON.ValueString()))
+		if err != nil {
+			return fmt.Errorf("%s: %w", dataFromJSON, err)
+		}
+		if err := addData(data, values, dataFromJSON); err != nil {
+			return err
+		}
+	}
+	if !m.DataFromYAML.IsNull() {
+		values, err := k8s.ParseYAMLData([]byte(m.DataFromYAML.ValueString()))
+		if err != nil {
+			return fmt.Errorf("%s: %w", dataFromYAML, err)
+		}
+		if err := addData(data, values, dataFromYAML); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func createSealedSecret(ctx context.Context, provider *ProviderConfig, d *schema.ResourceData) ([]byte, error) {
-	rawSecret := k8s.SecretManifest{
-		Name:      d.Get(name).(string),
-		Namespace: d.Get(namespace).(string),
-		Type:      d.Get(secretType).(string),
+// filesSha256 returns a HMAC-SHA256 of every value read from files, keyed with the provider hmac_key so short values
+// cannot be guessed from the state.
+func filesSha256(ctx context.Context, key []byte, files map[string]string, diags *diag.Diagnostics) types.Map {
+	if files == nil {
+		return types.MapNull(types.StringType)
 	}
-	if dataRaw, ok := d.GetOk(data); ok {
-		data := make(map[string]string)
-		for key, value := range dataRaw.(map[string]interface{}) {
-			data[key] = value.(string)
+	hashes, err := hmacData(key, files)
+	if err != nil {
+		diags.AddError("Unable to hash the data files",
+			"data_files, data_from_directory and data_from_env_file are only stored as HMACs in the state: "+err.Error())
+		return types.MapNull(types.StringType)
+	}
+	m, d := types.MapValueFrom(ctx, types.StringType, hashes)
+	diags.Append(d...)
+	return m
+}
+
+// setHelmValues renders encrypted_data under helm_values_key, or clears helm_values_yaml when no key is set.
+func (r *sealedSecretResource) setHelmValues(ctx context.Context, plan *sealedSecretModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if plan.HelmValuesKey.IsNull() || plan.HelmValuesKey.IsUnknown() || plan.EncryptedData.IsUnknown() {
+		plan.HelmValuesYaml = types.StringNull()
+		if plan.HelmValuesKey.IsUnknown() || (!plan.HelmValuesKey.IsNull() && plan.EncryptedData.IsUnknown()) {
+			plan.HelmValuesYaml = types.StringUnknown()
 		}
-		rawSecret.Data = data
+		return diags
 	}
 
-	secret, err := k8s.CreateSecret(&rawSecret)
+	encrypted := make(map[string]string)
+	diags.Append(plan.EncryptedData.ElementsAs(ctx, &encrypted, false)...)
+	if diags.HasError() {
+		return diags
+	}
+	values, err := helmValuesFor(plan.HelmValuesKey.ValueString(), encrypted)
 	if err != nil {
-		return nil, err
+		diags.AddAttributeError(path.Root(helmValuesKey), "Unable to render the Helm values", err.Error())
+		return diags
 	}
+	plan.HelmValuesYaml = types.StringValue(values)
+	return diags
+}
 
-	pk, err := getPublicKey(ctx, provider)
+func createSealedSecret(ctx context.Context, provider *ProviderConfig, rawSecret *k8s.SecretManifest, scope ssv1alpha1.SealingScope) ([]byte, *rsa.PublicKey, error) {
+	secret, err := k8s.CreateSecret(rawSecret)
+	if err != nil {
+		return nil, nil, err
+	}
+	kubeseal.SetScope(&secret, scope)
 
+	pk, err := getPublicKey(ctx, provider)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return kubeseal.SealSecret(secret, pk)
+	sealedSecret, err := kubeseal.SealSecret(secret, pk)
+	return sealedSecret, pk, err
 }
 
+// errNoSealingCert is returned by resolvePublicKey when the resolver found no key, so nothing is sealed with a nil one.
+var errNoSealingCert = errors.New("no usable sealing certificate")
+
+// getPublicKey waits for the controller and returns the key to seal with. An expiring certificate is only logged,
+// see planPublicKey.
 func getPublicKey(ctx context.Context, provider *ProviderConfig) (*rsa.PublicKey, error) {
+	pk, _, err := resolvePublicKey(ctx, provider)
+	return pk, err
+}
+
+// planPublicKey is getPublicKey for plan time, it reports an expiring certificate as a warning.
+func planPublicKey(ctx context.Context, provider *ProviderConfig) (*rsa.PublicKey, diag.Diagnostics) {
+	pk, expiry, err := resolvePublicKey(ctx, provider)
+	if err != nil && !errors.As(err, &expiry) {
+		var diags diag.Diagnostics
+		diags.AddError("Unable to fetch the public key", err.Error())
+		return nil, diags
+	}
+	return pk, expiryDiagnostics(expiry, pk == nil)
+}
+
+// checkCertificateExpiry reports an expiring certificate when a resource is created. The controller may be deployed
+// by the same apply, so it is asked only once and failures are left to the apply.
+func checkCertificateExpiry(ctx context.Context, provider *ProviderConfig) diag.Diagnostics {
+	pk, err := provider.PublicKeyResolver(ctx)
+	var expiry *kubeseal.ExpiryError
+	if !errors.As(err, &expiry) {
+		return nil
+	}
+	return expiryDiagnostics(expiry, pk == nil)
+}
+
+func expiryDiagnostics(expiry *kubeseal.ExpiryError, rejected bool) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if expiry == nil {
+		return diags
+	}
+	detail := fmt.Sprintf("The %s, secrets are sealed with it. The controller renews its key every 30 days, "+
+		"check that it is running and can rotate its keys.", expiry.Error())
+	switch {
+	case rejected:
+		diags.AddError("Controller certificate expired", detail+" Sealing is refused as reject_expired_certificate is set.")
+	case expiry.Expired:
+		diags.AddWarning("Controller certificate expired", detail)
+	default:
+		diags.AddWarning("Controller certificate expires soon", detail)
+	}
+	return diags
+}
+
+func resolvePublicKey(ctx context.Context, provider *ProviderConfig) (*rsa.PublicKey, *kubeseal.ExpiryError, error) {
 	var pk *rsa.PublicKey
-	err := resource.RetryContext(ctx, 3*time.Minute, func() *resource.RetryError {
-		var err error
-		logDebug("Trying to fetch the public key")
-		pk, err = provider.PublicKeyResolver(ctx)
-		if err != nil {
-			//TODO: refactor
-			if true || k8sErrors.IsNotFound(err) || k8sErrors.IsServiceUnavailable(err) {
-				logDebug("Retrying to fetch the public key: " + err.Error())
-				return resource.RetryableError(fmt.Errorf("waiting for sealed-secret-controller to be deployed: %w", err))
-			}
-			return resource.NonRetryableError(err)
+	var fetchErr error
+	attempt := 0
+	err := wait.PollUntilContextTimeout(ctx, pkFetchInterval, pkFetchTimeout, true, func(ctx context.Context) (bool, error) {
+		attempt++
+		tflog.Debug(ctx, "Fetching the public key", map[string]interface{}{"attempt": attempt})
+		pk, fetchErr = provider.PublicKeyResolver(ctx)
+		if pk != nil {
+			return true, nil
 		}
-		logDebug("Successfully fetched the public key")
-		return nil
+		if errors.Is(fetchErr, kubeseal.ErrCertNotFound) || errors.As(fetchErr, new(*kubeseal.ExpiryError)) {
+			return false, fetchErr
+		}
+		if fetchErr != nil {
+			tflog.Debug(ctx, "Retrying to fetch the public key", map[string]interface{}{"attempt": attempt, "error": fetchErr.Error()})
+			return false, nil
+		}
+		return true, nil
 	})
-	return pk, err
+	if pk != nil {
+		// the key is usable, an error only reports its certificate expiring
+		var expiry *kubeseal.ExpiryError
+		errors.As(fetchErr, &expiry)
+		return pk, expiry, nil
+	}
+	if errors.Is(fetchErr, kubeseal.ErrCertNotFound) || errors.As(fetchErr, new(*kubeseal.ExpiryError)) {
+		return nil, nil, fetchErr
+	}
+	if err != nil && fetchErr != nil {
+		return nil, nil, fmt.Errorf("waiting for sealed-secret-controller to be deployed: %w", fetchErr)
+	}
+	if err == nil {
+		// the resolver returned neither a key nor an error
+		err = errNoSealingCert
+	}
+	return nil, nil, err
 }
 
 // TODO: refactor
 func formatPublicKeyAsString(pk *rsa.PublicKey) string {
 	return strings.Join([]string{pk.N.String(), strconv.Itoa(pk.E)}, "::")
 }
-
-func logDebug(msg string) {
-	log.Printf("[DEBUG] %s\n", msg)
-}
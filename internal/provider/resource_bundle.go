@@ -0,0 +1,460 @@
+package provider
+
+import (
+	"context"
+	"crypto/rsa"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/mapvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/setvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/boolplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/setplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/jifwin/terraform-provider-sealedsecret/internal/k8s"
+	"github.com/jifwin/terraform-provider-sealedsecret/internal/kubeseal"
+	sigsyaml "sigs.k8s.io/yaml"
+)
+
+const (
+	secretBlock       = "secret"
+	documents         = "documents"
+	kustomization     = "kustomization"
+	kustomizationYaml = "kustomization_yaml"
+	documentSeparator = "---\n"
+)
+
+var (
+	_ resource.ResourceWithConfigure      = &sealedSecretBundleResource{}
+	_ resource.ResourceWithModifyPlan     = &sealedSecretBundleResource{}
+	_ resource.ResourceWithValidateConfig = &sealedSecretBundleResource{}
+)
+
+type sealedSecretBundleResource struct {
+	provider *ProviderConfig
+}
+
+type sealedSecretBundleModel struct {
+	ID                types.String        `tfsdk:"id"`
+	Namespace         types.String        `tfsdk:"namespace"`
+	Secrets           []bundleSecretModel `tfsdk:"secret"`
+	DataWO            types.Map           `tfsdk:"data_wo"`
+	DataWOVersion     types.Int64         `tfsdk:"data_wo_version"`
+	HashData          types.Bool          `tfsdk:"hash_data"`
+	DataHmac          types.Map           `tfsdk:"data_hmac"`
+	Kustomization     types.Bool          `tfsdk:"kustomization"`
+	YamlContent       types.String        `tfsdk:"yaml_content"`
+	Documents         types.Map           `tfsdk:"documents"`
+	KustomizationYaml types.String        `tfsdk:"kustomization_yaml"`
+	PublicKey         types.String        `tfsdk:"public_key"`
+	SealedWithFpr     types.String        `tfsdk:"sealed_with_fingerprint"`
+	NeedsReseal       types.Bool          `tfsdk:"needs_reseal"`
+}
+
+type bundleSecretModel struct {
+	Name types.String `tfsdk:"name"`
+	Type types.String `tfsdk:"type"`
+	Data types.Map    `tfsdk:"data"`
+}
+
+type kustomizationFile struct {
+	APIVersion string   `json:"apiVersion"`
+	Kind       string   `json:"kind"`
+	Resources  []string `json:"resources"`
+}
+
+func newSealedSecretBundleResource() resource.Resource {
+	return &sealedSecretBundleResource{}
+}
+
+func (r *sealedSecretBundleResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_bundle"
+}
+
+func (r *sealedSecretBundleResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Seals several secrets of a namespace into a single multi-document yaml_content, ordered by name.",
+		Attributes: map[string]schema.Attribute{
+			id: schema.StringAttribute{
+				Computed: true,
+			},
+			namespace: schema.StringAttribute{
+				Required:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
+				Validators:    []validator.String{namespaceValidator{}},
+				Description:   "namespace of the secrets",
+			},
+			dataWO: schema.MapAttribute{
+				ElementType: types.MapType{ElemType: types.StringType},
+				Optional:    true,
+				Sensitive:   true,
+				WriteOnly:   true,
+				Validators: []validator.Map{
+					mapvalidator.KeysAre(secretNameValidator{}),
+					mapvalidator.ValueMapsAre(secretDataValidator{}),
+				},
+				Description: "Write-only data of the secrets keyed by their name, used instead of the data of their block. The values are never " +
+					"stored in the state. Changes are detected through data_wo_version or, with hash_data, through data_hmac. Requires Terraform 1.11 or later.",
+			},
+			dataWOVersion: schema.Int64Attribute{
+				Optional:      true,
+				PlanModifiers: []planmodifier.Int64{int64planmodifier.RequiresReplace()},
+				Validators:    []validator.Int64{int64validator.AlsoRequires(path.MatchRoot(dataWO))},
+				Description:   "Bump this value to re-seal data_wo.",
+			},
+			hashData: schema.BoolAttribute{
+				Optional:      true,
+				Computed:      true,
+				Default:       booldefault.StaticBool(false),
+				PlanModifiers: []planmodifier.Bool{boolplanmodifier.RequiresReplace()},
+				Description:   "Store a HMAC of every data_wo value in data_hmac and re-seal when it changes. Requires the provider hmac_key.",
+			},
+			dataHmac: schema.MapAttribute{
+				ElementType: types.MapType{ElemType: types.StringType},
+				Computed:    true,
+				Description: "HMAC-SHA256 of every data_wo entry keyed by the secret name, set when hash_data is enabled.",
+			},
+			kustomization: schema.BoolAttribute{
+				Optional:      true,
+				Computed:      true,
+				Default:       booldefault.StaticBool(false),
+				PlanModifiers: []planmodifier.Bool{boolplanmodifier.RequiresReplace()},
+				Description:   "Produce kustomization_yaml listing the documents.",
+			},
+			yaml_content: schema.StringAttribute{
+				Computed:    true,
+				Description: "All sealed secrets as one multi-document yaml file, sorted by name.",
+			},
+			documents: schema.MapAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: "Every sealed secret on its own, keyed by the file name <name>.yaml.",
+			},
+			kustomizationYaml: schema.StringAttribute{
+				Computed:    true,
+				Description: "A kustomization.yaml listing the file names of documents, set when kustomization is enabled.",
+			},
+			public_key: schema.StringAttribute{
+				Computed:    true,
+				Description: "The key used for encryption",
+			},
+			sealedWithFpr: schema.StringAttribute{
+				Computed:    true,
+				Description: "SHA256 fingerprint of the controller key the secrets were sealed with.",
+			},
+			needsReseal: schema.BoolAttribute{
+				Computed:    true,
+				Description: "Whether the controller seals with a newer key than sealed_with_fingerprint. Only stays true when the provider's reseal_on_key_rotation is false.",
+			},
+		},
+		Blocks: map[string]schema.Block{
+			secretBlock: schema.SetNestedBlock{
+				Description:   "A secret to seal, the order of the blocks does not matter.",
+				Validators:    []validator.Set{setvalidator.IsRequired(), setvalidator.SizeAtLeast(1)},
+				PlanModifiers: []planmodifier.Set{setplanmodifier.RequiresReplace()},
+				NestedObject: schema.NestedBlockObject{
+					Attributes: map[string]schema.Attribute{
+						name: schema.StringAttribute{
+							Required:    true,
+							Validators:  []validator.String{secretNameValidator{}},
+							Description: "name of the secret, must be unique within the bundle",
+						},
+						secretType: schema.StringAttribute{
+							Optional:    true,
+							Description: "The secret type (ex. Opaque). Default type is Opaque.",
+						},
+						data: schema.MapAttribute{
+							ElementType: types.StringType,
+							Optional:    true,
+							Sensitive:   true,
+							Validators:  []validator.Map{secretDataValidator{}},
+							Description: "Key/value pairs to populate the secret, stored in the state. Leave it out to take the data from data_wo.",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (r *sealedSecretBundleResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+	r.provider = req.ProviderData.(*ProviderConfig)
+}
+
+func (r *sealedSecretBundleResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var cfg sealedSecretBundleModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &cfg)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if !cfg.DataWO.IsNull() && !cfg.HashData.ValueBool() && cfg.DataWOVersion.IsNull() {
+		resp.Diagnostics.AddAttributeWarning(path.Root(dataWO), "Changes to data_wo are not detected",
+			"Set data_wo_version or hash_data so the secrets are re-sealed when data_wo changes.")
+	}
+	dataWOKnown := !cfg.DataWO.IsUnknown()
+	dataWOSecrets := cfg.DataWO.Elements()
+	seen := map[string]bool{}
+	namesKnown := true
+	for _, secret := range cfg.Secrets {
+		if secret.Name.IsNull() || secret.Name.IsUnknown() {
+			namesKnown = false
+			continue
+		}
+		n := secret.Name.ValueString()
+		if seen[n] {
+			resp.Diagnostics.AddAttributeError(path.Root(secretBlock), "Duplicate secret name",
+				fmt.Sprintf("The bundle already contains a secret named %q.", n))
+		}
+		seen[n] = true
+
+		_, inDataWO := dataWOSecrets[n]
+		switch {
+		case !secret.Data.IsNull() && inDataWO:
+			resp.Diagnostics.AddAttributeError(path.Root(dataWO).AtMapKey(n), "Secret data set twice",
+				fmt.Sprintf("The secret %q has data in its block and in data_wo.", n))
+		case secret.Data.IsNull() && !inDataWO && dataWOKnown:
+			resp.Diagnostics.AddAttributeError(path.Root(secretBlock), "Missing secret data",
+				fmt.Sprintf("The secret %q needs data in its block or in data_wo.", n))
+		case !secret.Data.IsNull() && cfg.HashData.ValueBool():
+			resp.Diagnostics.AddAttributeError(path.Root(secretBlock), "data is stored in the state",
+				fmt.Sprintf("hash_data only keeps the values out of the state when they are passed through data_wo, the secret %q has data in its block.", n))
+		}
+	}
+	for n := range dataWOSecrets {
+		if !seen[n] && namesKnown {
+			resp.Diagnostics.AddAttributeError(path.Root(dataWO).AtMapKey(n), "Unknown secret",
+				fmt.Sprintf("data_wo has data for %q, which has no secret block.", n))
+		}
+	}
+}
+
+func (r *sealedSecretBundleResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() || r.provider == nil {
+		return
+	}
+	var plan sealedSecretBundleModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx = withLogging(ctx, bundleFields(plan.Namespace.ValueString()))
+	token := claimToken(ctx, resp.Private, &resp.Diagnostics)
+	for _, secret := range plan.Secrets {
+		r.provider.targets.claim(&resp.Diagnostics, sealingClaim, plan.Namespace.ValueString(), secret.Name.ValueString(),
+			"the sealedsecret_bundle of namespace "+plan.Namespace.ValueString(), token)
+	}
+	if req.State.Raw.IsNull() {
+		resp.Diagnostics.Append(checkCertificateExpiry(ctx, r.provider)...)
+		return
+	}
+
+	var state, cfg sealedSecretBundleModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	resp.Diagnostics.Append(req.Config.Get(ctx, &cfg)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	rotation, diags := checkKeyRotation(ctx, r.provider, state.PublicKey)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	var replace path.Paths
+	if rotation.stale {
+		addKeyRotationWarning(ctx, &resp.Diagnostics, r.provider, "sealedsecret_bundle "+state.ID.ValueString(), rotation)
+		if r.provider.ResealOnKeyRotation {
+			replace = append(replace, path.Root(public_key))
+		}
+	}
+
+	hashes := types.MapNull(types.MapType{ElemType: types.StringType})
+	if plan.HashData.ValueBool() {
+		if cfg.DataWO.IsUnknown() {
+			hashes = types.MapUnknown(types.MapType{ElemType: types.StringType})
+			replace = append(replace, path.Root(dataHmac))
+		} else {
+			hashes, diags = hashBundleDataWO(ctx, r.provider.HMACKey, cfg.DataWO)
+			resp.Diagnostics.Append(diags...)
+			if resp.Diagnostics.HasError() {
+				return
+			}
+			if !hashes.Equal(state.DataHmac) {
+				replace = append(replace, path.Root(dataHmac))
+			}
+		}
+	}
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root(dataHmac), hashes)...)
+
+	if len(replace) > 0 {
+		resp.RequiresReplace = append(resp.RequiresReplace, replace...)
+		for _, p := range []string{id, yaml_content, kustomizationYaml, public_key, sealedWithFpr} {
+			resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root(p), types.StringUnknown())...)
+		}
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root(documents), types.MapUnknown(types.StringType))...)
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root(needsReseal), false)...)
+		return
+	}
+	if !rotation.stale {
+		return
+	}
+	// only needs_reseal changes, the secrets are kept
+	for _, p := range []string{id, yaml_content, kustomizationYaml, public_key, sealedWithFpr, documents} {
+		var v attr.Value
+		resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root(p), &v)...)
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root(p), v)...)
+	}
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root(needsReseal), true)...)
+}
+
+func (r *sealedSecretBundleResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan, cfg sealedSecretBundleModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.Config.Get(ctx, &cfg)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	dataWO := make(map[string]map[string]string)
+	if !cfg.DataWO.IsNull() {
+		resp.Diagnostics.Append(cfg.DataWO.ElementsAs(ctx, &dataWO, false)...)
+	}
+
+	manifests := make([]k8s.SecretManifest, 0, len(plan.Secrets))
+	for _, secret := range plan.Secrets {
+		manifest := k8s.SecretManifest{
+			Name:      secret.Name.ValueString(),
+			Namespace: plan.Namespace.ValueString(),
+			Type:      stringOrDefault(secret.Type, defaultType),
+			Data:      dataWO[secret.Name.ValueString()],
+		}
+		if !secret.Data.IsNull() {
+			resp.Diagnostics.Append(secret.Data.ElementsAs(ctx, &manifest.Data, false)...)
+		}
+		manifests = append(manifests, manifest)
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx = withLogging(ctx, bundleFields(plan.Namespace.ValueString()))
+	for _, manifest := range manifests {
+		ctx = maskSecretValues(ctx, manifest.Data)
+	}
+
+	pk, err := getPublicKey(ctx, r.provider)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to fetch the public key", err.Error())
+		return
+	}
+	bundle, docs, err := sealBundle(pk, manifests)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to create the sealed secrets", err.Error())
+		return
+	}
+
+	plan.ID = bundleID(r.provider, plan.Namespace)
+	plan.YamlContent = types.StringValue(bundle)
+	plan.PublicKey = types.StringValue(formatPublicKeyAsString(pk))
+	plan.SealedWithFpr = types.StringValue(kubeseal.Fingerprint(pk))
+	plan.NeedsReseal = types.BoolValue(false)
+	plan.DataWO = types.MapNull(types.MapType{ElemType: types.StringType})
+	plan.DataHmac = types.MapNull(types.MapType{ElemType: types.StringType})
+	if plan.HashData.ValueBool() {
+		hashes, diags := hashBundleDataWO(ctx, r.provider.HMACKey, cfg.DataWO)
+		resp.Diagnostics.Append(diags...)
+		plan.DataHmac = hashes
+	}
+	documentsValue, diags := types.MapValueFrom(ctx, types.StringType, docs)
+	resp.Diagnostics.Append(diags...)
+	plan.Documents = documentsValue
+	plan.KustomizationYaml = types.StringNull()
+	if plan.Kustomization.ValueBool() {
+		fileNames := make([]string, 0, len(docs))
+		for fileName := range docs {
+			fileNames = append(fileNames, fileName)
+		}
+		k, err := kustomizationFor(fileNames)
+		if err != nil {
+			resp.Diagnostics.AddError("Unable to create the kustomization", err.Error())
+			return
+		}
+		plan.KustomizationYaml = types.StringValue(k)
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *sealedSecretBundleResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	// Nothing is stored remotely, public key changes are detected in ModifyPlan.
+	// Bundles created by older versions get the fingerprint of the key they were sealed with.
+	var state sealedSecretBundleModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() || !state.SealedWithFpr.IsNull() {
+		return
+	}
+	fingerprint, err := storedKeyFingerprint(state.PublicKey.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to read the public key", err.Error())
+		return
+	}
+	state.SealedWithFpr = types.StringValue(fingerprint)
+	state.NeedsReseal = types.BoolValue(false)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
+
+func (r *sealedSecretBundleResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan sealedSecretBundleModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *sealedSecretBundleResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+}
+
+// sealBundle seals the secrets and returns them as one multi-document yaml sorted by name, and as documents keyed
+// by their file name.
+func sealBundle(pk *rsa.PublicKey, manifests []k8s.SecretManifest) (string, map[string]string, error) {
+	sort.Slice(manifests, func(i, j int) bool { return manifests[i].Name < manifests[j].Name })
+
+	sealed := make([]string, 0, len(manifests))
+	docs := make(map[string]string, len(manifests))
+	for i := range manifests {
+		secret, err := k8s.CreateSecret(&manifests[i])
+		if err != nil {
+			return "", nil, fmt.Errorf("secret %s: %w", manifests[i].Name, err)
+		}
+		sealedSecret, err := kubeseal.SealSecret(secret, pk)
+		if err != nil {
+			return "", nil, fmt.Errorf("secret %s: %w", manifests[i].Name, err)
+		}
+		sealed = append(sealed, string(sealedSecret))
+		docs[manifests[i].Name+".yaml"] = string(sealedSecret)
+	}
+	return strings.Join(sealed, documentSeparator), docs, nil
+}
+
+// kustomizationFor returns a kustomization.yaml listing the given files as resources.
+func kustomizationFor(fileNames []string) (string, error) {
+	k := kustomizationFile{APIVersion: "kustomize.config.k8s.io/v1beta1", Kind: "Kustomization"}
+	k.Resources = append(k.Resources, fileNames...)
+	sort.Strings(k.Resources)
+	out, err := sigsyaml.Marshal(k)
+	return string(out), err
+}
+
+// bundleFields identifies the bundle in the log, like secretFields.
+func bundleFields(namespace string) map[string]interface{} {
+	return map[string]interface{}{"namespace": namespace, "resource_address": "sealedsecret_bundle " + namespace}
+}
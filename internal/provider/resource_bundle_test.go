@@ -0,0 +1,145 @@
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	ssv1alpha1 "github.com/bitnami-labs/sealed-secrets/pkg/apis/sealedsecrets/v1alpha1"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/jifwin/terraform-provider-sealedsecret/internal/k8s"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+func TestSealBundle(t *testing.T) {
+	manifests := []k8s.SecretManifest{
+		{Name: "name_ccc", Namespace: "ns_aaa", Type: defaultType, Data: map[string]string{"secret": "secret_ccc"}},
+		{Name: "name_aaa", Namespace: "ns_aaa", Type: defaultType, Data: map[string]string{"secret": "secret_aaa"}},
+		{Name: "name_bbb", Namespace: "ns_aaa", Type: "kubernetes.io/basic-auth", Data: map[string]string{"username": "user_bbb"}},
+	}
+
+	bundle, docs, err := sealBundle(testPublicKey(t), manifests)
+	assert.Nil(t, err)
+
+	parts := strings.Split(bundle, documentSeparator)
+	assert.Len(t, parts, 3)
+	for i, n := range []string{"name_aaa", "name_bbb", "name_ccc"} {
+		var ss ssv1alpha1.SealedSecret
+		assert.Nil(t, yaml.Unmarshal([]byte(parts[i]), &ss))
+		assert.Equal(t, n, ss.Name)
+		assert.Equal(t, "ns_aaa", ss.Namespace)
+		assert.Equal(t, parts[i], docs[n+".yaml"])
+	}
+
+	_, _, err = sealBundle(testPublicKey(t), []k8s.SecretManifest{{Name: "name_aaa", Namespace: "ns_aaa"}})
+	assert.ErrorContains(t, err, "secret name_aaa")
+}
+
+func TestKustomizationFor(t *testing.T) {
+	k, err := kustomizationFor([]string{"name_bbb.yaml", "name_aaa.yaml"})
+	assert.Nil(t, err)
+	assert.Equal(t, `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+resources:
+- name_aaa.yaml
+- name_bbb.yaml
+`, k)
+}
+
+func bundleDataWO(t *testing.T, m map[string]map[string]string) types.Map {
+	v, diags := types.MapValueFrom(context.Background(), types.MapType{ElemType: types.StringType}, m)
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+	return v
+}
+
+func TestBundleDataWO(t *testing.T) {
+	ctx := context.Background()
+	r := &sealedSecretBundleResource{provider: testProviderConfig(t, testPublicKey(t))}
+	s := resourceSchema(t, r).Schema
+	dataWO := map[string]map[string]string{"name_aaa": {"secret": "secret_aaa"}}
+	model := sealedSecretBundleModel{
+		ID:        types.StringUnknown(),
+		Namespace: types.StringValue("ns_aaa"),
+		Secrets: []bundleSecretModel{
+			{Name: types.StringValue("name_aaa"), Type: types.StringNull(), Data: types.MapNull(types.StringType)},
+			{Name: types.StringValue("name_bbb"), Type: types.StringNull(), Data: stringMap(t, map[string]string{"secret": "secret_bbb"})},
+		},
+		DataWO:            bundleDataWO(t, dataWO),
+		DataWOVersion:     types.Int64Null(),
+		HashData:          types.BoolValue(true),
+		DataHmac:          types.MapUnknown(types.MapType{ElemType: types.StringType}),
+		Kustomization:     types.BoolValue(false),
+		YamlContent:       types.StringUnknown(),
+		Documents:         types.MapUnknown(types.StringType),
+		KustomizationYaml: types.StringUnknown(),
+		PublicKey:         types.StringUnknown(),
+		SealedWithFpr:     types.StringUnknown(),
+		NeedsReseal:       types.BoolUnknown(),
+	}
+	plan := tfsdk.State{Schema: s}
+	assert.False(t, plan.Set(ctx, &model).HasError())
+
+	resp := resource.CreateResponse{State: tfsdk.State{Schema: s}}
+	r.Create(ctx, resource.CreateRequest{
+		Plan:   tfsdk.Plan{Schema: s, Raw: plan.Raw},
+		Config: tfsdk.Config{Schema: s, Raw: plan.Raw},
+	}, &resp)
+	assert.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+	var created sealedSecretBundleModel
+	assert.False(t, resp.State.Get(ctx, &created).HasError())
+	assert.True(t, created.DataWO.IsNull(), "data_wo is never stored")
+	assert.NotContains(t, resp.State.Raw.String(), "secret_aaa")
+	hashes, err := hmacData(r.provider.HMACKey, dataWO["name_aaa"])
+	assert.Nil(t, err)
+	assert.Equal(t, bundleDataWO(t, map[string]map[string]string{"name_aaa": hashes}), created.DataHmac)
+
+	var ss ssv1alpha1.SealedSecret
+	assert.Nil(t, yaml.Unmarshal([]byte(created.Documents.Elements()["name_aaa.yaml"].(types.String).ValueString()), &ss))
+	assert.Contains(t, ss.Spec.EncryptedData, "secret")
+
+	// the created bundle as state, planned with changed data_wo
+	state := resp.State
+	tests := []struct {
+		Name                    string
+		DataWO                  types.Map
+		ExpectedRequiresReplace path.Paths
+	}{
+		{
+			Name:   "unchanged data_wo does not replace the bundle",
+			DataWO: bundleDataWO(t, dataWO),
+		},
+		{
+			Name:                    "changed data_wo replaces the bundle",
+			DataWO:                  bundleDataWO(t, map[string]map[string]string{"name_aaa": {"secret": "secret_ccc"}}),
+			ExpectedRequiresReplace: path.Paths{path.Root(dataHmac)},
+		},
+		{
+			Name:                    "unknown data_wo replaces the bundle",
+			DataWO:                  types.MapUnknown(types.MapType{ElemType: types.StringType}),
+			ExpectedRequiresReplace: path.Paths{path.Root(dataHmac)},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			cfg := created
+			cfg.DataWO = test.DataWO
+			config := tfsdk.State{Schema: s}
+			assert.False(t, config.Set(ctx, &cfg).HasError())
+
+			resp := modifyPlanResponse(tfsdk.Plan{Schema: s, Raw: state.Raw})
+			r.ModifyPlan(ctx, resource.ModifyPlanRequest{
+				State:  state,
+				Config: tfsdk.Config{Schema: s, Raw: config.Raw},
+				Plan:   tfsdk.Plan{Schema: s, Raw: state.Raw},
+			}, &resp)
+			assert.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+			assert.Equal(t, test.ExpectedRequiresReplace, resp.RequiresReplace)
+		})
+	}
+}
@@ -0,0 +1,273 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/jifwin/terraform-provider-sealedsecret/internal/k8s"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	waitForSynced      = "wait_for_synced"
+	waitTimeout        = "wait_timeout"
+	defaultWaitTimeout = "5m"
+)
+
+var (
+	_ resource.ResourceWithConfigure   = &sealedSecretInClusterResource{}
+	_ resource.ResourceWithModifyPlan  = &sealedSecretInClusterResource{}
+	_ resource.ResourceWithImportState = &sealedSecretInClusterResource{}
+)
+
+type sealedSecretInClusterResource struct {
+	provider *ProviderConfig
+}
+
+type sealedSecretInClusterModel struct {
+	ID            types.String `tfsdk:"id"`
+	Name          types.String `tfsdk:"name"`
+	Namespace     types.String `tfsdk:"namespace"`
+	YamlContent   types.String `tfsdk:"yaml_content"`
+	WaitForSynced types.Bool   `tfsdk:"wait_for_synced"`
+	WaitTimeout   types.String `tfsdk:"wait_timeout"`
+}
+
+func newSealedSecretInClusterResource() resource.Resource {
+	return &sealedSecretInClusterResource{}
+}
+
+func (r *sealedSecretInClusterResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_in_cluster"
+}
+
+func (r *sealedSecretInClusterResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Applies a SealedSecret manifest, e.g. the yaml_content of a sealedsecret resource, to the cluster with server-side apply.",
+		Attributes: map[string]schema.Attribute{
+			id: schema.StringAttribute{
+				Computed:      true,
+				Description:   "The namespace and name of the SealedSecret, separated by a slash.",
+				PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+			},
+			yaml_content: schema.StringAttribute{
+				Required:    true,
+				Description: "The SealedSecret manifest to apply. Changes made in the cluster are detected and reverted.",
+			},
+			name: schema.StringAttribute{
+				Computed:    true,
+				Description: "Name of the SealedSecret, taken from the manifest.",
+			},
+			namespace: schema.StringAttribute{
+				Computed:    true,
+				Description: "Namespace of the SealedSecret, taken from the manifest.",
+			},
+			waitForSynced: schema.BoolAttribute{
+				Optional:    true,
+				Computed:    true,
+				Default:     booldefault.StaticBool(false),
+				Description: "Wait until the controller has unsealed the secret and reports the Synced condition.",
+			},
+			waitTimeout: schema.StringAttribute{
+				Optional:    true,
+				Computed:    true,
+				Default:     stringdefault.StaticString(defaultWaitTimeout),
+				Validators:  []validator.String{durationValidator{}},
+				Description: "How long to wait for the controller when wait_for_synced is set, e.g. 30s or 5m. Defaults to 5m.",
+			},
+		},
+	}
+}
+
+func (r *sealedSecretInClusterResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+	r.provider = req.ProviderData.(*ProviderConfig)
+	if r.provider.SealedSecrets == nil {
+		resp.Diagnostics.AddError("Missing kubernetes configuration",
+			"sealedsecret_in_cluster applies to the cluster, which needs the kubernetes block of the provider")
+	}
+}
+
+// ModifyPlan derives name and namespace from the manifest, moving the object to another name or namespace replaces it.
+func (r *sealedSecretInClusterResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+	var plan sealedSecretInClusterModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() || plan.YamlContent.IsUnknown() {
+		return
+	}
+
+	obj, err := k8s.DecodeSealedSecret([]byte(plan.YamlContent.ValueString()))
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root(yaml_content), "Invalid SealedSecret manifest", err.Error())
+		return
+	}
+	plan.Name = types.StringValue(obj.GetName())
+	plan.Namespace = types.StringValue(obj.GetNamespace())
+	if r.provider != nil {
+		r.provider.targets.claim(&resp.Diagnostics, applyingClaim, obj.GetNamespace(), obj.GetName(),
+			"a sealedsecret_in_cluster resource", claimToken(ctx, resp.Private, &resp.Diagnostics))
+	}
+
+	if !req.State.Raw.IsNull() {
+		var state sealedSecretInClusterModel
+		resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		if !plan.Name.Equal(state.Name) || !plan.Namespace.Equal(state.Namespace) {
+			resp.RequiresReplace = append(resp.RequiresReplace, path.Root(yaml_content))
+			plan.ID = types.StringUnknown()
+		}
+	}
+	resp.Diagnostics.Append(resp.Plan.Set(ctx, &plan)...)
+}
+
+func (r *sealedSecretInClusterResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan sealedSecretInClusterModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx = withLogging(ctx, secretFields("sealedsecret_in_cluster", plan.Namespace.ValueString(), plan.Name.ValueString()))
+
+	if err := r.apply(ctx, &plan); err != nil {
+		resp.Diagnostics.AddError("Unable to apply the SealedSecret", err.Error())
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+	r.waitForSynced(ctx, &plan, &resp.Diagnostics)
+}
+
+func (r *sealedSecretInClusterResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state sealedSecretInClusterModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx = withLogging(ctx, secretFields("sealedsecret_in_cluster", state.Namespace.ValueString(), state.Name.ValueString()))
+
+	live, err := r.provider.SealedSecrets.GetSealedSecret(ctx, state.Namespace.ValueString(), state.Name.ValueString())
+	if k8sErrors.IsNotFound(err) {
+		tflog.Debug(ctx, "SealedSecret no longer exists")
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to read the SealedSecret", err.Error())
+		return
+	}
+
+	var desired *unstructured.Unstructured
+	if !state.YamlContent.IsNull() {
+		desired, err = k8s.DecodeSealedSecret([]byte(state.YamlContent.ValueString()))
+	}
+	if desired == nil || err != nil || !k8s.SealedSecretSpecEqual(desired, live) {
+		tflog.Debug(ctx, "SealedSecret differs from the applied manifest")
+		manifest, err := k8s.EncodeSealedSecret(live)
+		if err != nil {
+			resp.Diagnostics.AddError("Unable to encode the SealedSecret", err.Error())
+			return
+		}
+		state.YamlContent = types.StringValue(string(manifest))
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
+
+func (r *sealedSecretInClusterResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan sealedSecretInClusterModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx = withLogging(ctx, secretFields("sealedsecret_in_cluster", plan.Namespace.ValueString(), plan.Name.ValueString()))
+
+	if err := r.apply(ctx, &plan); err != nil {
+		resp.Diagnostics.AddError("Unable to apply the SealedSecret", err.Error())
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+	r.waitForSynced(ctx, &plan, &resp.Diagnostics)
+}
+
+func (r *sealedSecretInClusterResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state sealedSecretInClusterModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx = withLogging(ctx, secretFields("sealedsecret_in_cluster", state.Namespace.ValueString(), state.Name.ValueString()))
+
+	if err := r.provider.SealedSecrets.DeleteSealedSecret(ctx, state.Namespace.ValueString(), state.Name.ValueString()); err != nil {
+		resp.Diagnostics.AddError("Unable to delete the SealedSecret", err.Error())
+	}
+}
+
+func (r *sealedSecretInClusterResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	ns, n, ok := strings.Cut(req.ID, "/")
+	if !ok || ns == "" || n == "" {
+		resp.Diagnostics.AddError("Invalid import ID", fmt.Sprintf("expected <namespace>/<name>, got %q", req.ID))
+		return
+	}
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root(id), req.ID)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root(namespace), ns)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root(name), n)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root(waitForSynced), false)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root(waitTimeout), defaultWaitTimeout)...)
+}
+
+func (r *sealedSecretInClusterResource) apply(ctx context.Context, plan *sealedSecretInClusterModel) error {
+	obj, err := k8s.DecodeSealedSecret([]byte(plan.YamlContent.ValueString()))
+	if err != nil {
+		return err
+	}
+	if _, err := r.provider.SealedSecrets.ApplySealedSecret(ctx, obj); err != nil {
+		return err
+	}
+	plan.ID = types.StringValue(obj.GetNamespace() + "/" + obj.GetName())
+	plan.Name = types.StringValue(obj.GetName())
+	plan.Namespace = types.StringValue(obj.GetNamespace())
+	return nil
+}
+
+// waitForSynced runs after the state was saved, so a SealedSecret the controller fails to unseal is tainted rather than orphaned.
+func (r *sealedSecretInClusterResource) waitForSynced(ctx context.Context, plan *sealedSecretInClusterModel, diags *diag.Diagnostics) {
+	if !plan.WaitForSynced.ValueBool() {
+		return
+	}
+	timeout, err := time.ParseDuration(plan.WaitTimeout.ValueString())
+	if err != nil {
+		diags.AddAttributeError(path.Root(waitTimeout), "Invalid duration", err.Error())
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err = r.provider.SealedSecrets.WaitForUnseal(ctx, plan.Namespace.ValueString(), plan.Name.ValueString())
+	switch {
+	case errors.Is(err, k8s.ErrUnsealFailed):
+		diags.AddError("The controller failed to unseal the SealedSecret",
+			err.Error()+"\n\nThe SealedSecret was most likely sealed with a key the controller does not have, or for another name or namespace.")
+	case err != nil:
+		diags.AddError("SealedSecret was not unsealed in time", err.Error())
+	}
+}
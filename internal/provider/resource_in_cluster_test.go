@@ -0,0 +1,140 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/jifwin/terraform-provider-sealedsecret/internal/k8s"
+	"github.com/stretchr/testify/assert"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const testInClusterManifest = `apiVersion: bitnami.com/v1alpha1
+kind: SealedSecret
+metadata:
+  name: name_aaa
+  namespace: ns_aaa
+spec:
+  encryptedData:
+    secret: c2VhbGVk
+  template:
+    metadata:
+      creationTimestamp: null
+      name: name_aaa
+      namespace: ns_aaa
+`
+
+type fakeSealedSecrets struct {
+	objects   map[string]*unstructured.Unstructured
+	unsealErr error
+}
+
+func (f *fakeSealedSecrets) ApplySealedSecret(ctx context.Context, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	f.objects[obj.GetNamespace()+"/"+obj.GetName()] = obj.DeepCopy()
+	return obj, nil
+}
+
+func (f *fakeSealedSecrets) GetSealedSecret(ctx context.Context, namespace, name string) (*unstructured.Unstructured, error) {
+	obj, ok := f.objects[namespace+"/"+name]
+	if !ok {
+		return nil, k8sErrors.NewNotFound(k8s.SealedSecretGVR.GroupResource(), name)
+	}
+	return obj, nil
+}
+
+func (f *fakeSealedSecrets) DeleteSealedSecret(ctx context.Context, namespace, name string) error {
+	delete(f.objects, namespace+"/"+name)
+	return nil
+}
+
+func (f *fakeSealedSecrets) WaitForUnseal(ctx context.Context, namespace, name string) error {
+	return f.unsealErr
+}
+
+func TestSealedSecretInClusterLifecycle(t *testing.T) {
+	ctx := context.Background()
+	cluster := &fakeSealedSecrets{objects: map[string]*unstructured.Unstructured{}}
+	r := &sealedSecretInClusterResource{provider: &ProviderConfig{SealedSecrets: cluster}}
+	s := resourceSchema(t, r).Schema
+
+	plan := tfsdk.State{Schema: s}
+	assert.False(t, plan.Set(ctx, &sealedSecretInClusterModel{
+		ID:            types.StringUnknown(),
+		Name:          types.StringUnknown(),
+		Namespace:     types.StringUnknown(),
+		YamlContent:   types.StringValue(testInClusterManifest),
+		WaitForSynced: types.BoolValue(true),
+		WaitTimeout:   types.StringValue(defaultWaitTimeout),
+	}).HasError())
+
+	createResp := resource.CreateResponse{State: tfsdk.State{Schema: s}}
+	r.Create(ctx, resource.CreateRequest{Plan: tfsdk.Plan{Schema: s, Raw: plan.Raw}}, &createResp)
+	assert.False(t, createResp.Diagnostics.HasError(), createResp.Diagnostics)
+	var created sealedSecretInClusterModel
+	assert.False(t, createResp.State.Get(ctx, &created).HasError())
+	assert.Equal(t, "ns_aaa/name_aaa", created.ID.ValueString())
+	assert.Contains(t, cluster.objects, "ns_aaa/name_aaa")
+
+	read := func() (sealedSecretInClusterModel, bool) {
+		resp := resource.ReadResponse{State: createResp.State}
+		r.Read(ctx, resource.ReadRequest{State: createResp.State}, &resp)
+		assert.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+		var m sealedSecretInClusterModel
+		if resp.State.Raw.IsNull() {
+			return m, false
+		}
+		assert.False(t, resp.State.Get(ctx, &m).HasError())
+		return m, true
+	}
+
+	// the API server drops the null creationTimestamp, which is not drift
+	unstructured.RemoveNestedField(cluster.objects["ns_aaa/name_aaa"].Object, "spec", "template", "metadata", "creationTimestamp")
+	m, exists := read()
+	assert.True(t, exists)
+	assert.Equal(t, testInClusterManifest, m.YamlContent.ValueString())
+
+	assert.Nil(t, unstructured.SetNestedField(cluster.objects["ns_aaa/name_aaa"].Object, "Y2hhbmdlZA==", "spec", "encryptedData", "secret"))
+	m, exists = read()
+	assert.True(t, exists)
+	assert.NotEqual(t, testInClusterManifest, m.YamlContent.ValueString())
+	assert.Contains(t, m.YamlContent.ValueString(), "Y2hhbmdlZA==")
+
+	deleteResp := resource.DeleteResponse{}
+	r.Delete(ctx, resource.DeleteRequest{State: createResp.State}, &deleteResp)
+	assert.False(t, deleteResp.Diagnostics.HasError(), deleteResp.Diagnostics)
+	_, exists = read()
+	assert.False(t, exists)
+}
+
+func TestSealedSecretInClusterUnsealFailed(t *testing.T) {
+	ctx := context.Background()
+	cluster := &fakeSealedSecrets{
+		objects:   map[string]*unstructured.Unstructured{},
+		unsealErr: fmt.Errorf("%w ns_aaa/name_aaa: no key could decrypt secret", k8s.ErrUnsealFailed),
+	}
+	r := &sealedSecretInClusterResource{provider: &ProviderConfig{SealedSecrets: cluster}}
+	s := resourceSchema(t, r).Schema
+
+	plan := tfsdk.State{Schema: s}
+	assert.False(t, plan.Set(ctx, &sealedSecretInClusterModel{
+		ID:            types.StringUnknown(),
+		Name:          types.StringUnknown(),
+		Namespace:     types.StringUnknown(),
+		YamlContent:   types.StringValue(testInClusterManifest),
+		WaitForSynced: types.BoolValue(true),
+		WaitTimeout:   types.StringValue("30s"),
+	}).HasError())
+
+	resp := resource.CreateResponse{State: tfsdk.State{Schema: s}}
+	r.Create(ctx, resource.CreateRequest{Plan: tfsdk.Plan{Schema: s, Raw: plan.Raw}}, &resp)
+	assert.True(t, resp.Diagnostics.HasError())
+	assert.Equal(t, "The controller failed to unseal the SealedSecret", resp.Diagnostics[0].Summary())
+	assert.Contains(t, resp.Diagnostics[0].Detail(), "no key could decrypt secret")
+	// the object was applied, so it is kept in the state to be tainted
+	assert.False(t, resp.State.Raw.IsNull())
+}
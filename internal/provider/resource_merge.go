@@ -0,0 +1,356 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	ssv1alpha1 "github.com/bitnami-labs/sealed-secrets/pkg/apis/sealedsecrets/v1alpha1"
+	"github.com/hashicorp/terraform-plugin-framework-validators/resourcevalidator"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/boolplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/mapplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/setplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/jifwin/terraform-provider-sealedsecret/internal/kubeseal"
+)
+
+const (
+	removeKeys        = "remove_keys"
+	mergedYamlContent = "merged_yaml_content"
+)
+
+var (
+	_ resource.ResourceWithConfigure        = &sealedSecretMergeResource{}
+	_ resource.ResourceWithConfigValidators = &sealedSecretMergeResource{}
+	_ resource.ResourceWithModifyPlan       = &sealedSecretMergeResource{}
+	_ resource.ResourceWithValidateConfig   = &sealedSecretMergeResource{}
+)
+
+type sealedSecretMergeResource struct {
+	provider *ProviderConfig
+}
+
+type sealedSecretMergeModel struct {
+	ID                types.String `tfsdk:"id"`
+	YamlContent       types.String `tfsdk:"yaml_content"`
+	Data              types.Map    `tfsdk:"data"`
+	DataWO            types.Map    `tfsdk:"data_wo"`
+	DataWOVersion     types.Int64  `tfsdk:"data_wo_version"`
+	HashData          types.Bool   `tfsdk:"hash_data"`
+	DataHmac          types.Map    `tfsdk:"data_hmac"`
+	RemoveKeys        types.Set    `tfsdk:"remove_keys"`
+	Name              types.String `tfsdk:"name"`
+	Namespace         types.String `tfsdk:"namespace"`
+	MergedYamlContent types.String `tfsdk:"merged_yaml_content"`
+	PublicKey         types.String `tfsdk:"public_key"`
+	SealedWithFpr     types.String `tfsdk:"sealed_with_fingerprint"`
+	NeedsReseal       types.Bool   `tfsdk:"needs_reseal"`
+}
+
+func newSealedSecretMergeResource() resource.Resource {
+	return &sealedSecretMergeResource{}
+}
+
+func (r *sealedSecretMergeResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_merge"
+}
+
+func (r *sealedSecretMergeResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Adds keys to and removes keys from an existing SealedSecret manifest, like kubeseal --merge-into. " +
+			"The keys that are kept stay sealed as they are, so their plaintext is not needed.",
+		Attributes: map[string]schema.Attribute{
+			id: schema.StringAttribute{
+				Computed: true,
+			},
+			yaml_content: schema.StringAttribute{
+				Required:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
+				Description:   "The SealedSecret manifest to merge into, e.g. read from a file in Git.",
+			},
+			data: schema.MapAttribute{
+				ElementType:   types.StringType,
+				Optional:      true,
+				Sensitive:     true,
+				Validators:    []validator.Map{secretDataValidator{}},
+				PlanModifiers: []planmodifier.Map{mapplanmodifier.RequiresReplace()},
+				Description:   "Key/value pairs to seal into the manifest, existing keys are replaced.",
+			},
+			dataWO: schema.MapAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Sensitive:   true,
+				WriteOnly:   true,
+				Validators:  []validator.Map{secretDataValidator{}},
+				Description: "Write-only variant of data, the values are never stored in the state. " +
+					"Changes are detected through data_wo_version or, with hash_data, through data_hmac. Requires Terraform 1.11 or later.",
+			},
+			dataWOVersion: schema.Int64Attribute{
+				Optional:      true,
+				PlanModifiers: []planmodifier.Int64{int64planmodifier.RequiresReplace()},
+				Description:   "Bump this value to merge data_wo again.",
+			},
+			hashData: schema.BoolAttribute{
+				Optional:      true,
+				Computed:      true,
+				Default:       booldefault.StaticBool(false),
+				PlanModifiers: []planmodifier.Bool{boolplanmodifier.RequiresReplace()},
+				Description:   "Store a HMAC of every data_wo value in data_hmac and merge again when it changes. Requires the provider hmac_key.",
+			},
+			dataHmac: schema.MapAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: "HMAC-SHA256 of every data_wo entry, set when hash_data is enabled.",
+			},
+			removeKeys: schema.SetAttribute{
+				ElementType:   types.StringType,
+				Optional:      true,
+				PlanModifiers: []planmodifier.Set{setplanmodifier.RequiresReplace()},
+				Description:   "Keys to drop from the manifest.",
+			},
+			name: schema.StringAttribute{
+				Computed:    true,
+				Description: "Name of the SealedSecret, taken from the manifest.",
+			},
+			namespace: schema.StringAttribute{
+				Computed:    true,
+				Description: "Namespace of the SealedSecret, taken from the manifest.",
+			},
+			mergedYamlContent: schema.StringAttribute{
+				Computed:    true,
+				Description: "The merged SealedSecret manifest.",
+			},
+			public_key: schema.StringAttribute{
+				Computed:    true,
+				Description: "The key used for encrypting the new keys",
+			},
+			sealedWithFpr: schema.StringAttribute{
+				Computed:    true,
+				Description: "SHA256 fingerprint of the controller key the new keys were sealed with.",
+			},
+			needsReseal: schema.BoolAttribute{
+				Computed: true,
+				Description: "Whether the controller seals with a newer key than sealed_with_fingerprint. Only stays true when the " +
+					"provider's reseal_on_key_rotation is false. Resealing merges the new keys again, the kept keys stay as they are.",
+			},
+		},
+	}
+}
+
+func (r *sealedSecretMergeResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+	r.provider = req.ProviderData.(*ProviderConfig)
+}
+
+func (r *sealedSecretMergeResource) ConfigValidators(ctx context.Context) []resource.ConfigValidator {
+	return []resource.ConfigValidator{
+		resourcevalidator.Conflicting(path.MatchRoot(data), path.MatchRoot(dataWO)),
+		resourcevalidator.Conflicting(path.MatchRoot(data), path.MatchRoot(dataWOVersion)),
+	}
+}
+
+func (r *sealedSecretMergeResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var cfg sealedSecretMergeModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &cfg)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if cfg.HashData.ValueBool() && !cfg.Data.IsNull() {
+		resp.Diagnostics.AddAttributeError(path.Root(data), "data is stored in the state",
+			"hash_data only keeps the values out of the state when they are passed through data_wo.")
+	}
+	if !cfg.DataWO.IsNull() && !cfg.HashData.ValueBool() && cfg.DataWOVersion.IsNull() {
+		resp.Diagnostics.AddAttributeWarning(path.Root(dataWO), "Changes to data_wo are not detected",
+			"Set data_wo_version or hash_data so the keys are merged again when data_wo changes.")
+	}
+	if cfg.RemoveKeys.IsUnknown() {
+		return
+	}
+	for _, attribute := range []struct {
+		name   string
+		values types.Map
+	}{{data, cfg.Data}, {dataWO, cfg.DataWO}} {
+		keys := attribute.values.Elements()
+		for _, v := range cfg.RemoveKeys.Elements() {
+			key, ok := v.(types.String)
+			if !ok || key.IsUnknown() {
+				continue
+			}
+			if _, ok := keys[key.ValueString()]; ok {
+				resp.Diagnostics.AddAttributeError(path.Root(removeKeys), "Key both set and removed",
+					fmt.Sprintf("The key %q is in %s and in remove_keys.", key.ValueString(), attribute.name))
+			}
+		}
+	}
+}
+
+// ModifyPlan derives name, namespace and id from the manifest and merges again when data_wo or the controller key
+// changed.
+func (r *sealedSecretMergeResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+	var plan, state, cfg sealedSecretMergeModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.Config.Get(ctx, &cfg)...)
+	if resp.Diagnostics.HasError() || plan.YamlContent.IsUnknown() {
+		return
+	}
+
+	sealedSecret, err := kubeseal.ParseSealedSecret([]byte(plan.YamlContent.ValueString()))
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root(yaml_content), "Invalid SealedSecret manifest", err.Error())
+		return
+	}
+	if sealedSecret.Namespace == "" && ssv1alpha1.SecretScope(sealedSecret) != ssv1alpha1.ClusterWideScope &&
+		(!cfg.Data.IsNull() || !cfg.DataWO.IsNull()) {
+		resp.Diagnostics.AddAttributeError(path.Root(yaml_content), "Invalid SealedSecret manifest", kubeseal.ErrNoNamespace.Error())
+		return
+	}
+	plan.Name = types.StringValue(sealedSecret.Name)
+	plan.Namespace = types.StringValue(sealedSecret.Namespace)
+	plan.ID = secretID(r.provider, plan.Namespace, plan.Name)
+	if r.provider != nil {
+		r.provider.targets.claim(&resp.Diagnostics, mergingClaim, sealedSecret.Namespace, sealedSecret.Name,
+			"a sealedsecret_merge resource", claimToken(ctx, resp.Private, &resp.Diagnostics))
+	}
+	if r.provider == nil || req.State.Raw.IsNull() {
+		if r.provider != nil {
+			resp.Diagnostics.Append(checkCertificateExpiry(ctx, r.provider)...)
+		}
+		resp.Diagnostics.Append(resp.Plan.Set(ctx, &plan)...)
+		return
+	}
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx = withLogging(ctx, secretFields("sealedsecret_merge", sealedSecret.Namespace, sealedSecret.Name))
+
+	var replace path.Paths
+	rotation, diags := checkKeyRotation(ctx, r.provider, state.PublicKey)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if rotation.stale {
+		addKeyRotationWarning(ctx, &resp.Diagnostics, r.provider, "sealedsecret_merge "+state.ID.ValueString(), rotation)
+		if r.provider.ResealOnKeyRotation {
+			replace = append(replace, path.Root(public_key))
+		}
+	}
+
+	if plan.HashData.ValueBool() {
+		if cfg.DataWO.IsUnknown() {
+			plan.DataHmac = types.MapUnknown(types.StringType)
+			replace = append(replace, path.Root(dataHmac))
+		} else {
+			hashes, diags := hashDataWO(ctx, r.provider.HMACKey, cfg.DataWO)
+			resp.Diagnostics.Append(diags...)
+			if resp.Diagnostics.HasError() {
+				return
+			}
+			plan.DataHmac = hashes
+			if !hashes.Equal(state.DataHmac) {
+				replace = append(replace, path.Root(dataHmac))
+			}
+		}
+	}
+
+	if len(replace) > 0 {
+		plan.MergedYamlContent = types.StringUnknown()
+		plan.PublicKey = types.StringUnknown()
+		plan.SealedWithFpr = types.StringUnknown()
+		plan.NeedsReseal = types.BoolValue(false)
+		resp.RequiresReplace = append(resp.RequiresReplace, replace...)
+	} else {
+		// only the id can change in place, the merged manifest is kept
+		plan.MergedYamlContent = state.MergedYamlContent
+		plan.PublicKey = state.PublicKey
+		plan.SealedWithFpr = state.SealedWithFpr
+		plan.NeedsReseal = types.BoolValue(rotation.stale)
+		if !plan.HashData.ValueBool() {
+			plan.DataHmac = state.DataHmac
+		}
+	}
+	resp.Diagnostics.Append(resp.Plan.Set(ctx, &plan)...)
+}
+
+func (r *sealedSecretMergeResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan, cfg sealedSecretMergeModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.Config.Get(ctx, &cfg)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	secretData := plan.Data
+	if !cfg.DataWO.IsNull() {
+		secretData = cfg.DataWO
+	}
+	var values map[string]string
+	var remove []string
+	resp.Diagnostics.Append(secretData.ElementsAs(ctx, &values, false)...)
+	resp.Diagnostics.Append(plan.RemoveKeys.ElementsAs(ctx, &remove, false)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx = maskSecretValues(ctx, values)
+
+	manifest := []byte(plan.YamlContent.ValueString())
+	sealedSecret, err := kubeseal.ParseSealedSecret(manifest)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root(yaml_content), "Invalid SealedSecret manifest", err.Error())
+		return
+	}
+	ctx = withLogging(ctx, secretFields("sealedsecret_merge", sealedSecret.Namespace, sealedSecret.Name))
+
+	pk, err := getPublicKey(ctx, r.provider)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to fetch the public key", err.Error())
+		return
+	}
+	merged, err := kubeseal.MergeSealedSecret(manifest, pk, values, remove)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to merge the sealed secret", err.Error())
+		return
+	}
+
+	plan.Name = types.StringValue(sealedSecret.Name)
+	plan.Namespace = types.StringValue(sealedSecret.Namespace)
+	plan.ID = secretID(r.provider, plan.Namespace, plan.Name)
+	plan.MergedYamlContent = types.StringValue(string(merged))
+	plan.PublicKey = types.StringValue(formatPublicKeyAsString(pk))
+	plan.SealedWithFpr = types.StringValue(kubeseal.Fingerprint(pk))
+	plan.NeedsReseal = types.BoolValue(false)
+	plan.DataWO = types.MapNull(types.StringType)
+	plan.DataHmac = types.MapNull(types.StringType)
+	if plan.HashData.ValueBool() {
+		hashes, diags := hashDataWO(ctx, r.provider.HMACKey, cfg.DataWO)
+		resp.Diagnostics.Append(diags...)
+		plan.DataHmac = hashes
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *sealedSecretMergeResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	// Nothing is stored remotely, public key changes are detected in ModifyPlan.
+}
+
+func (r *sealedSecretMergeResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan sealedSecretMergeModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *sealedSecretMergeResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+}
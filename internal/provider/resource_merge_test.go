@@ -0,0 +1,118 @@
+package provider
+
+import (
+	"context"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/assert"
+)
+
+const testSealedSecretManifest = `apiVersion: bitnami.com/v1alpha1
+kind: SealedSecret
+metadata:
+  name: name_aaa
+  namespace: ns_aaa
+spec:
+  encryptedData:
+    password: sealed_aaa
+`
+
+func TestMergeModifyPlan(t *testing.T) {
+	ctx := context.Background()
+	pk := testPublicKey(t)
+	r := &sealedSecretMergeResource{provider: testProviderConfig(t, pk)}
+	s := resourceSchema(t, r).Schema
+
+	hashes, err := hmacData(r.provider.HMACKey, map[string]string{"user": "user_aaa"})
+	assert.Nil(t, err)
+	state := sealedSecretMergeModel{
+		ID:                types.StringValue("ns_aaa/name_aaa"),
+		YamlContent:       types.StringValue(testSealedSecretManifest),
+		Data:              types.MapNull(types.StringType),
+		DataWO:            types.MapNull(types.StringType),
+		DataWOVersion:     types.Int64Null(),
+		HashData:          types.BoolValue(true),
+		DataHmac:          stringMap(t, hashes),
+		RemoveKeys:        types.SetNull(types.StringType),
+		Name:              types.StringValue("name_aaa"),
+		Namespace:         types.StringValue("ns_aaa"),
+		MergedYamlContent: types.StringValue("merged_aaa"),
+		PublicKey:         types.StringValue(formatPublicKeyAsString(pk)),
+		SealedWithFpr:     types.StringValue("SHA256:aaa"),
+		NeedsReseal:       types.BoolValue(false),
+	}
+
+	tests := []struct {
+		Name                    string
+		DataWO                  map[string]string
+		PublicKey               *rsa.PublicKey
+		KeepOnRotation          bool
+		ExpectedRequiresReplace path.Paths
+		ExpectedNeedsReseal     bool
+	}{
+		{
+			Name:      "unchanged data_wo keeps the merged manifest",
+			DataWO:    map[string]string{"user": "user_aaa"},
+			PublicKey: pk,
+		},
+		{
+			Name:                    "changed data_wo merges again",
+			DataWO:                  map[string]string{"user": "user_bbb"},
+			PublicKey:               pk,
+			ExpectedRequiresReplace: path.Paths{path.Root(dataHmac)},
+		},
+		{
+			Name:                    "new public key merges again",
+			DataWO:                  map[string]string{"user": "user_aaa"},
+			PublicKey:               testPublicKey(t),
+			ExpectedRequiresReplace: path.Paths{path.Root(public_key)},
+		},
+		{
+			Name:                "new public key only marks the resource when resealing is disabled",
+			DataWO:              map[string]string{"user": "user_aaa"},
+			PublicKey:           testPublicKey(t),
+			KeepOnRotation:      true,
+			ExpectedNeedsReseal: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			r.provider = testProviderConfig(t, tc.PublicKey)
+			r.provider.ResealOnKeyRotation = !tc.KeepOnRotation
+
+			priorState := tfsdk.State{Schema: s}
+			assert.False(t, priorState.Set(ctx, &state).HasError())
+
+			cfgModel := state
+			cfgModel.DataWO = stringMap(t, tc.DataWO)
+			cfg := tfsdk.State{Schema: s}
+			assert.False(t, cfg.Set(ctx, &cfgModel).HasError())
+
+			req := resource.ModifyPlanRequest{
+				Config: tfsdk.Config{Schema: s, Raw: cfg.Raw},
+				Plan:   tfsdk.Plan{Schema: s, Raw: priorState.Raw},
+				State:  priorState,
+			}
+			resp := modifyPlanResponse(req.Plan)
+			r.ModifyPlan(ctx, req, &resp)
+
+			assert.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+			assert.Equal(t, len(tc.ExpectedRequiresReplace), len(resp.RequiresReplace))
+			for _, p := range tc.ExpectedRequiresReplace {
+				assert.True(t, resp.RequiresReplace.Contains(p))
+			}
+
+			var plan sealedSecretMergeModel
+			assert.False(t, resp.Plan.Get(ctx, &plan).HasError())
+			assert.Equal(t, len(tc.ExpectedRequiresReplace) > 0, plan.MergedYamlContent.IsUnknown())
+			assert.Equal(t, tc.ExpectedNeedsReseal, plan.NeedsReseal.ValueBool())
+			assert.Equal(t, tc.PublicKey != pk, resp.Diagnostics.WarningsCount() == 1)
+		})
+	}
+}
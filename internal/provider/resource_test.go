@@ -0,0 +1,541 @@
+package provider
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	ssv1alpha1 "github.com/bitnami-labs/sealed-secrets/pkg/apis/sealedsecrets/v1alpha1"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/jifwin/terraform-provider-sealedsecret/internal/k8s"
+	"github.com/jifwin/terraform-provider-sealedsecret/internal/kubeseal"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+func testProviderConfig(t *testing.T, pk *rsa.PublicKey) *ProviderConfig {
+	return &ProviderConfig{
+		ControllerName:      "name",
+		ControllerNamespace: "ns",
+		PublicKeyResolver: func(ctx context.Context) (*rsa.PublicKey, error) {
+			return pk, nil
+		},
+		HMACKey:             []byte("key_aaa"),
+		ResealOnKeyRotation: true,
+		targets:             &secretTargets{},
+		stale:               &staleResources{},
+	}
+}
+
+func testPublicKey(t *testing.T) *rsa.PublicKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &key.PublicKey
+}
+
+func resourceSchema(t *testing.T, r resource.Resource) resource.SchemaResponse {
+	var resp resource.SchemaResponse
+	r.Schema(context.Background(), resource.SchemaRequest{}, &resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatal(resp.Diagnostics)
+	}
+	return resp
+}
+
+func stringMap(t *testing.T, m map[string]string) types.Map {
+	v, diags := types.MapValueFrom(context.Background(), types.StringType, m)
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+	return v
+}
+
+func TestModifyPlan(t *testing.T) {
+	ctx := context.Background()
+	pk := testPublicKey(t)
+	r := &sealedSecretResource{provider: testProviderConfig(t, pk)}
+	s := resourceSchema(t, r).Schema
+
+	hashes, err := hmacData(r.provider.HMACKey, map[string]string{"secret": "secret_aaa"})
+	assert.Nil(t, err)
+	state := sealedSecretModel{
+		ID:              types.StringValue("name_aaa"),
+		Name:            types.StringValue("name_aaa"),
+		Namespace:       types.StringValue("ns_aaa"),
+		Type:            types.StringValue(defaultType),
+		Data:            types.MapNull(types.StringType),
+		DataWO:          types.MapNull(types.StringType),
+		DataWOVersion:   types.Int64Null(),
+		HashData:        types.BoolValue(true),
+		DataHmac:        stringMap(t, hashes),
+		YamlContent:     types.StringValue("yaml_aaa"),
+		PublicKey:       types.StringValue(formatPublicKeyAsString(pk)),
+		EncryptedData:   stringMap(t, map[string]string{"secret": "sealed_aaa"}),
+		DataFiles:       types.MapNull(types.StringType),
+		DataFilesSha256: types.MapNull(types.StringType),
+	}
+
+	tests := []struct {
+		Name                    string
+		DataWO                  map[string]string
+		PublicKey               *rsa.PublicKey
+		KeepOnRotation          bool
+		ExpectedRequiresReplace path.Paths
+		ExpectedNeedsReseal     bool
+	}{
+		{
+			Name:      "unchanged data_wo does not replace the resource",
+			DataWO:    map[string]string{"secret": "secret_aaa"},
+			PublicKey: pk,
+		},
+		{
+			Name:                    "changed data_wo replaces the resource",
+			DataWO:                  map[string]string{"secret": "secret_bbb"},
+			PublicKey:               pk,
+			ExpectedRequiresReplace: path.Paths{path.Root(dataHmac)},
+		},
+		{
+			Name:                    "added key replaces the resource",
+			DataWO:                  map[string]string{"secret": "secret_aaa", "other": "other_aaa"},
+			PublicKey:               pk,
+			ExpectedRequiresReplace: path.Paths{path.Root(dataHmac)},
+		},
+		{
+			Name:                    "new public key replaces the resource",
+			DataWO:                  map[string]string{"secret": "secret_aaa"},
+			PublicKey:               testPublicKey(t),
+			ExpectedRequiresReplace: path.Paths{path.Root(public_key)},
+		},
+		{
+			Name:                "new public key only marks the resource when resealing is disabled",
+			DataWO:              map[string]string{"secret": "secret_aaa"},
+			PublicKey:           testPublicKey(t),
+			KeepOnRotation:      true,
+			ExpectedNeedsReseal: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			r.provider = testProviderConfig(t, tc.PublicKey)
+			r.provider.ResealOnKeyRotation = !tc.KeepOnRotation
+
+			priorState := tfsdk.State{Schema: s}
+			assert.False(t, priorState.Set(ctx, &state).HasError())
+
+			cfgModel := state
+			cfgModel.DataWO = stringMap(t, tc.DataWO)
+			cfg := tfsdk.State{Schema: s}
+			assert.False(t, cfg.Set(ctx, &cfgModel).HasError())
+
+			req := resource.ModifyPlanRequest{
+				Config: tfsdk.Config{Schema: s, Raw: cfg.Raw},
+				Plan:   tfsdk.Plan{Schema: s, Raw: priorState.Raw},
+				State:  priorState,
+			}
+			resp := modifyPlanResponse(req.Plan)
+			r.ModifyPlan(ctx, req, &resp)
+
+			assert.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+			assert.Equal(t, len(tc.ExpectedRequiresReplace), len(resp.RequiresReplace))
+			for _, p := range tc.ExpectedRequiresReplace {
+				assert.True(t, resp.RequiresReplace.Contains(p))
+			}
+
+			var plan sealedSecretModel
+			assert.False(t, resp.Plan.Get(ctx, &plan).HasError())
+			assert.Equal(t, len(tc.ExpectedRequiresReplace) > 0, plan.YamlContent.IsUnknown())
+			assert.Equal(t, tc.ExpectedNeedsReseal, plan.NeedsReseal.ValueBool())
+			assert.Equal(t, "ns_aaa/name_aaa", plan.ID.ValueString(), "ids of older versions are updated")
+			rotated := tc.PublicKey != pk
+			assert.Equal(t, rotated, resp.Diagnostics.WarningsCount() == 1)
+			if rotated {
+				assert.Equal(t, keyRotationSummary+": 1 resource", resp.Diagnostics.Warnings()[0].Summary())
+				assert.Contains(t, resp.Diagnostics.Warnings()[0].Detail(), kubeseal.Fingerprint(tc.PublicKey))
+				assert.Contains(t, resp.Diagnostics.Warnings()[0].Detail(), kubeseal.Fingerprint(pk))
+			}
+		})
+	}
+}
+
+func TestUpgradeStateV0(t *testing.T) {
+	ctx := context.Background()
+	r := &sealedSecretResource{}
+	upgrader := r.UpgradeState(ctx)[0]
+
+	prior := tfsdk.State{
+		Schema: upgrader.PriorSchema,
+		Raw: tftypes.NewValue(upgrader.PriorSchema.Type().TerraformType(ctx), map[string]tftypes.Value{
+			id:           tftypes.NewValue(tftypes.String, "name_aaa"),
+			name:         tftypes.NewValue(tftypes.String, "name_aaa"),
+			namespace:    tftypes.NewValue(tftypes.String, "ns_aaa"),
+			secretType:   tftypes.NewValue(tftypes.String, "Opaque"),
+			data:         tftypes.NewValue(tftypes.Map{ElementType: tftypes.String}, map[string]tftypes.Value{"secret": tftypes.NewValue(tftypes.String, "secret_aaa")}),
+			hashData:     tftypes.NewValue(tftypes.Bool, nil),
+			dataHmac:     tftypes.NewValue(tftypes.Map{ElementType: tftypes.String}, nil),
+			yaml_content: tftypes.NewValue(tftypes.String, "yaml_aaa"),
+			public_key:   tftypes.NewValue(tftypes.String, "pk_aaa"),
+		}),
+	}
+
+	req := resource.UpgradeStateRequest{State: &prior}
+	resp := resource.UpgradeStateResponse{State: tfsdk.State{Schema: resourceSchema(t, r).Schema}}
+	upgrader.StateUpgrader(ctx, req, &resp)
+	assert.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+
+	var upgraded sealedSecretModel
+	assert.False(t, resp.State.Get(ctx, &upgraded).HasError())
+	assert.Equal(t, "ns_aaa/name_aaa", upgraded.ID.ValueString())
+	assert.Equal(t, "yaml_aaa", upgraded.YamlContent.ValueString())
+	assert.Equal(t, "pk_aaa", upgraded.PublicKey.ValueString())
+	assert.False(t, upgraded.HashData.ValueBool())
+	assert.Equal(t, stringMap(t, map[string]string{"secret": "secret_aaa"}), upgraded.Data)
+	assert.True(t, upgraded.DataWO.IsNull())
+}
+
+func TestUpgradeStateV1(t *testing.T) {
+	ctx := context.Background()
+	r := &sealedSecretResource{}
+	s := resourceSchema(t, r).Schema
+	upgrader := r.UpgradeState(ctx)[1]
+
+	prior := tfsdk.State{Schema: *upgrader.PriorSchema}
+	assert.False(t, prior.Set(ctx, &sealedSecretModel{
+		ID:              types.StringValue("name-aaa"),
+		Name:            types.StringValue("name-aaa"),
+		Namespace:       types.StringValue("ns-aaa"),
+		Type:            types.StringValue(defaultType),
+		Data:            stringMap(t, map[string]string{"secret": "secret_aaa"}),
+		DataWO:          types.MapNull(types.StringType),
+		DataFiles:       types.MapNull(types.StringType),
+		DataFilesSha256: types.MapNull(types.StringType),
+		DataHmac:        types.MapNull(types.StringType),
+		EncryptedData:   types.MapNull(types.StringType),
+		YamlContent:     types.StringValue("yaml_aaa"),
+		PublicKey:       types.StringValue("1::65537"),
+	}).HasError())
+
+	resp := resource.UpgradeStateResponse{State: tfsdk.State{Schema: s}}
+	upgrader.StateUpgrader(ctx, resource.UpgradeStateRequest{State: &prior}, &resp)
+	assert.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+
+	var upgraded sealedSecretModel
+	assert.False(t, resp.State.Get(ctx, &upgraded).HasError())
+	assert.Equal(t, "ns-aaa/name-aaa", upgraded.ID.ValueString())
+	assert.Equal(t, "yaml_aaa", upgraded.YamlContent.ValueString())
+}
+
+func TestSecretID(t *testing.T) {
+	provider := testProviderConfig(t, nil)
+	assert.Equal(t, "ns-aaa/name-aaa", secretID(provider, types.StringValue("ns-aaa"), types.StringValue("name-aaa")).ValueString())
+	assert.Equal(t, "ns-aaa", bundleID(provider, types.StringValue("ns-aaa")).ValueString())
+	provider.MultipleControllers = true
+	assert.Equal(t, "ns-aaa/name-aaa@ns/name", secretID(provider, types.StringValue("ns-aaa"), types.StringValue("name-aaa")).ValueString())
+	assert.Equal(t, "ns-aaa@ns/name", bundleID(provider, types.StringValue("ns-aaa")).ValueString())
+	assert.True(t, secretID(provider, types.StringValue("ns-aaa"), types.StringUnknown()).IsUnknown())
+	assert.True(t, bundleID(provider, types.StringUnknown()).IsUnknown())
+}
+
+func TestDuplicateSecretWarning(t *testing.T) {
+	targets := &secretTargets{}
+	var diags diag.Diagnostics
+	targets.claim(&diags, sealingClaim, "ns-aaa", "name-aaa", "a sealedsecret resource", "token_aaa")
+	targets.claim(&diags, sealingClaim, "ns-bbb", "name-aaa", "a sealedsecret resource", "token_bbb")
+	targets.claim(&diags, sealingClaim, "ns-aaa", "name-bbb", "a sealedsecret resource", "token_ccc")
+	// a replacement plans the resource again with a null prior state
+	targets.claim(&diags, sealingClaim, "ns-aaa", "name-aaa", "a sealedsecret resource", "token_aaa")
+	// the manifest of a sealedsecret resource is applied and merged by other resources
+	targets.claim(&diags, applyingClaim, "ns-aaa", "name-aaa", "a sealedsecret_in_cluster resource", "token_ddd")
+	targets.claim(&diags, mergingClaim, "ns-aaa", "name-aaa", "a sealedsecret_merge resource", "token_eee")
+	assert.Empty(t, diags)
+
+	// copies of the same configuration are still different resources
+	targets.claim(&diags, sealingClaim, "ns-aaa", "name-aaa", "a sealedsecret resource", "token_fff")
+	assert.Len(t, diags, 1)
+	assert.Equal(t, "Secret sealed by more than one resource", diags[0].Summary())
+	assert.Contains(t, diags[0].Detail(), "ns-aaa/name-aaa is sealed by more than one sealedsecret resource")
+
+	targets.claim(&diags, sealingClaim, "ns-bbb", "name-aaa", "the sealedsecret_bundle of namespace ns-bbb", "token_ggg")
+	assert.Len(t, diags, 2)
+	assert.Contains(t, diags[1].Detail(), "ns-bbb/name-aaa is sealed by a sealedsecret resource and by the sealedsecret_bundle")
+
+	targets.claim(&diags, applyingClaim, "ns-aaa", "name-aaa", "a sealedsecret_in_cluster resource", "token_hhh")
+	targets.claim(&diags, mergingClaim, "ns-aaa", "name-aaa", "a sealedsecret_merge resource", "token_iii")
+	assert.Len(t, diags, 4)
+	assert.Equal(t, "Secret applied by more than one resource", diags[2].Summary())
+	assert.Equal(t, "Secret merged by more than one resource", diags[3].Summary())
+}
+
+func TestClaimToken(t *testing.T) {
+	ctx := context.Background()
+	var diags diag.Diagnostics
+	resp := modifyPlanResponse(tfsdk.Plan{})
+	token := claimToken(ctx, resp.Private, &diags)
+	assert.Len(t, token, 32)
+	assert.Equal(t, token, claimToken(ctx, resp.Private, &diags), "the token is kept in the private state")
+
+	other := modifyPlanResponse(tfsdk.Plan{})
+	assert.NotEqual(t, token, claimToken(ctx, other.Private, &diags))
+	assert.Empty(t, diags)
+}
+
+func TestModifyPlanClaimsSecret(t *testing.T) {
+	ctx := context.Background()
+	r := &sealedSecretResource{provider: testProviderConfig(t, testPublicKey(t))}
+	s := resourceSchema(t, r).Schema
+	plan := tfsdk.State{Schema: s}
+	assert.False(t, plan.Set(ctx, &sealedSecretModel{
+		ID:                types.StringUnknown(),
+		Name:              types.StringValue("name-aaa"),
+		Namespace:         types.StringValue("ns-aaa"),
+		Type:              types.StringValue(defaultType),
+		Data:              stringMap(t, map[string]string{"secret": "secret_aaa"}),
+		DataWO:            types.MapNull(types.StringType),
+		HashData:          types.BoolValue(false),
+		DataHmac:          types.MapUnknown(types.StringType),
+		NameSuffixHash:    types.BoolValue(false),
+		SecretName:        types.StringUnknown(),
+		KustomizationYaml: types.StringUnknown(),
+		HelmValuesYaml:    types.StringUnknown(),
+		EncryptedData:     types.MapUnknown(types.StringType),
+		DataFiles:         types.MapNull(types.StringType),
+		DataFilesSha256:   types.MapUnknown(types.StringType),
+		YamlContent:       types.StringUnknown(),
+		PublicKey:         types.StringUnknown(),
+	}).HasError())
+	req := resource.ModifyPlanRequest{Config: tfsdk.Config{Schema: s, Raw: plan.Raw}, Plan: tfsdk.Plan{Schema: s, Raw: plan.Raw}}
+
+	first := modifyPlanResponse(req.Plan)
+	r.ModifyPlan(ctx, req, &first)
+	assert.Empty(t, first.Diagnostics)
+
+	// the create half of a replacement gets the planned private state back
+	again := modifyPlanResponse(req.Plan)
+	again.Private = first.Private
+	r.ModifyPlan(ctx, req, &again)
+	assert.Empty(t, again.Diagnostics)
+
+	// a copy of the resource with the same configuration
+	copied := modifyPlanResponse(req.Plan)
+	r.ModifyPlan(ctx, req, &copied)
+	assert.Equal(t, 1, copied.Diagnostics.WarningsCount())
+}
+
+func TestHashDataRequiresKey(t *testing.T) {
+	_, diags := hashDataWO(context.Background(), nil, stringMap(t, map[string]string{"secret": "secret_aaa"}))
+	assert.True(t, diags.HasError())
+	assert.Contains(t, diags[0].Detail(), errMissingHMACKey.Error())
+}
+
+// modifyPlanResponse returns a response with an empty private state, which the framework always passes to
+// ModifyPlan but cannot be created outside of it.
+func modifyPlanResponse(plan tfsdk.Plan) resource.ModifyPlanResponse {
+	resp := resource.ModifyPlanResponse{Plan: plan}
+	private := reflect.ValueOf(&resp.Private).Elem()
+	private.Set(reflect.New(private.Type().Elem()))
+	return resp
+}
+
+// testCreate runs Create with model as plan and config and returns the new state.
+func testCreate(t *testing.T, r *sealedSecretResource, model sealedSecretModel) sealedSecretModel {
+	ctx := context.Background()
+	s := resourceSchema(t, r).Schema
+	plan := tfsdk.State{Schema: s}
+	assert.False(t, plan.Set(ctx, &model).HasError())
+
+	resp := resource.CreateResponse{State: tfsdk.State{Schema: s}}
+	r.Create(ctx, resource.CreateRequest{
+		Plan:   tfsdk.Plan{Schema: s, Raw: plan.Raw},
+		Config: tfsdk.Config{Schema: s, Raw: plan.Raw},
+	}, &resp)
+	assert.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+
+	var created sealedSecretModel
+	assert.False(t, resp.State.Get(ctx, &created).HasError())
+	return created
+}
+
+func TestCreateNameSuffixHash(t *testing.T) {
+	r := &sealedSecretResource{provider: testProviderConfig(t, testPublicKey(t))}
+	model := sealedSecretModel{
+		ID:                types.StringUnknown(),
+		Name:              types.StringValue("name-aaa"),
+		Namespace:         types.StringValue("ns_aaa"),
+		Type:              types.StringValue(defaultType),
+		Data:              stringMap(t, map[string]string{"secret": "secret_aaa"}),
+		DataWO:            types.MapNull(types.StringType),
+		DataWOVersion:     types.Int64Null(),
+		HashData:          types.BoolValue(false),
+		DataHmac:          types.MapUnknown(types.StringType),
+		NameSuffixHash:    types.BoolValue(true),
+		SecretName:        types.StringUnknown(),
+		KustomizationYaml: types.StringUnknown(),
+		HelmValuesKey:     types.StringValue("sealedSecret.encryptedData"),
+		HelmValuesYaml:    types.StringUnknown(),
+		EncryptedData:     types.MapUnknown(types.StringType),
+		DataFiles:         types.MapNull(types.StringType),
+		DataFilesSha256:   types.MapNull(types.StringType),
+		YamlContent:       types.StringUnknown(),
+		PublicKey:         types.StringUnknown(),
+	}
+	created := testCreate(t, r, model)
+	assert.Equal(t, "ns_aaa/name-aaa", created.ID.ValueString())
+	assert.Regexp(t, `^name-aaa-[a-z0-9]{10}$`, created.SecretName.ValueString())
+	assert.Contains(t, created.KustomizationYaml.ValueString(), "- "+created.SecretName.ValueString()+".yaml")
+
+	var ss ssv1alpha1.SealedSecret
+	assert.Nil(t, yaml.Unmarshal([]byte(created.YamlContent.ValueString()), &ss))
+	assert.Equal(t, created.SecretName.ValueString(), ss.Name)
+	assert.Equal(t, ssv1alpha1.NamespaceWideScope, ss.Scope())
+	assert.Equal(t, stringMap(t, ss.Spec.EncryptedData), created.EncryptedData)
+	assert.Equal(t, "sealedSecret:\n  encryptedData:\n    secret: "+ss.Spec.EncryptedData["secret"]+"\n", created.HelmValuesYaml.ValueString())
+
+	hashed, err := k8s.HashedName(&k8s.SecretManifest{Name: "name-aaa", Type: defaultType, Data: map[string]string{"secret": "secret_aaa"}}, r.provider.HMACKey)
+	assert.Nil(t, err)
+	assert.Equal(t, hashed, created.SecretName.ValueString(), "the suffix is keyed with hmac_key")
+
+	// without hmac_key the suffix could be used to guess the data
+	ctx := context.Background()
+	r.provider.HMACKey = nil
+	s := resourceSchema(t, r).Schema
+	plan := tfsdk.State{Schema: s}
+	assert.False(t, plan.Set(ctx, &model).HasError())
+	resp := modifyPlanResponse(tfsdk.Plan{Schema: s, Raw: plan.Raw})
+	r.ModifyPlan(ctx, resource.ModifyPlanRequest{Config: tfsdk.Config{Schema: s, Raw: plan.Raw}, Plan: resp.Plan}, &resp)
+	if assert.True(t, resp.Diagnostics.HasError()) {
+		assert.Equal(t, errMissingHMACKey.Error(), resp.Diagnostics[0].Detail())
+	}
+}
+
+func TestCreateFromDocuments(t *testing.T) {
+	r := &sealedSecretResource{provider: testProviderConfig(t, testPublicKey(t))}
+	created := testCreate(t, r, sealedSecretModel{
+		ID:                types.StringUnknown(),
+		Name:              types.StringValue("name_aaa"),
+		Namespace:         types.StringValue("ns_aaa"),
+		Type:              types.StringValue(defaultType),
+		Data:              types.MapNull(types.StringType),
+		DataWO:            types.MapNull(types.StringType),
+		HashData:          types.BoolValue(false),
+		DataHmac:          types.MapUnknown(types.StringType),
+		NameSuffixHash:    types.BoolValue(false),
+		SecretName:        types.StringUnknown(),
+		KustomizationYaml: types.StringUnknown(),
+		HelmValuesYaml:    types.StringUnknown(),
+		EncryptedData:     types.MapUnknown(types.StringType),
+		DataFiles:         types.MapNull(types.StringType),
+		DataFilesSha256:   types.MapNull(types.StringType),
+		DataFromJSON:      types.StringValue(`{"user": "user_aaa"}`),
+		DataFromYAML:      types.StringValue("port: 5432\n"),
+		YamlContent:       types.StringUnknown(),
+		PublicKey:         types.StringUnknown(),
+	})
+
+	keys := make(map[string]string)
+	assert.False(t, created.EncryptedData.ElementsAs(context.Background(), &keys, false).HasError())
+	assert.Contains(t, keys, "user")
+	assert.Contains(t, keys, "port")
+}
+
+func TestPlanPublicKeyExpiry(t *testing.T) {
+	pk := testPublicKey(t)
+	expiring := &kubeseal.ExpiryError{Subject: "CN=sealed-secret", Fingerprint: kubeseal.Fingerprint(pk), NotAfter: time.Now().Add(time.Hour)}
+	expired := &kubeseal.ExpiryError{Subject: "CN=sealed-secret", Fingerprint: kubeseal.Fingerprint(pk), NotAfter: time.Now().Add(-time.Hour), Expired: true}
+
+	tests := []struct {
+		name     string
+		pk       *rsa.PublicKey
+		err      error
+		severity diag.Severity
+		summary  string
+	}{
+		{name: "valid", pk: pk},
+		{name: "expiring", pk: pk, err: expiring, severity: diag.SeverityWarning, summary: "Controller certificate expires soon"},
+		{name: "expired", pk: pk, err: expired, severity: diag.SeverityWarning, summary: "Controller certificate expired"},
+		{name: "rejected", err: expired, severity: diag.SeverityError, summary: "Controller certificate expired"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			provider := testProviderConfig(t, pk)
+			provider.PublicKeyResolver = func(ctx context.Context) (*rsa.PublicKey, error) {
+				return tc.pk, tc.err
+			}
+
+			resolved, planDiags := planPublicKey(context.Background(), provider)
+			assert.Equal(t, tc.pk, resolved)
+			for _, diags := range []diag.Diagnostics{planDiags, checkCertificateExpiry(context.Background(), provider)} {
+				if tc.err == nil {
+					assert.Empty(t, diags)
+					continue
+				}
+				assert.Len(t, diags, 1)
+				assert.Equal(t, tc.severity, diags[0].Severity())
+				assert.Equal(t, tc.summary, diags[0].Summary())
+				assert.Contains(t, diags[0].Detail(), tc.err.Error())
+			}
+		})
+	}
+}
+
+func TestPlanPublicKeyWithoutCertificate(t *testing.T) {
+	provider := testProviderConfig(t, nil)
+
+	pk, diags := planPublicKey(context.Background(), provider)
+	assert.Nil(t, pk)
+	if assert.True(t, diags.HasError()) {
+		assert.Equal(t, errNoSealingCert.Error(), diags[0].Detail())
+	}
+	_, err := getPublicKey(context.Background(), provider)
+	assert.ErrorIs(t, err, errNoSealingCert)
+}
+
+func TestKeyRotationSummary(t *testing.T) {
+	ctx := context.Background()
+	provider := testProviderConfig(t, testPublicKey(t))
+	provider.stale.settle = 100 * time.Millisecond
+	rotation := keyRotation{stale: true, sealedWith: "fpr_aaa", current: "fpr_bbb"}
+
+	// resources planned together are reported once, by the last one
+	resources := []string{"sealedsecret ns_aaa/name_aaa", "sealedsecret_bundle ns_aaa", "sealedsecret_merge ns_aaa/name_aaa"}
+	results := make([]diag.Diagnostics, len(resources))
+	var wg sync.WaitGroup
+	for i, resource := range resources {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			addKeyRotationWarning(ctx, &results[i], provider, resource, rotation)
+		}()
+	}
+	wg.Wait()
+	var diags diag.Diagnostics
+	for _, d := range results {
+		diags.Append(d...)
+	}
+	if assert.Len(t, diags, 1) {
+		assert.Equal(t, keyRotationSummary+": 3 resources", diags[0].Summary())
+		assert.Contains(t, diags[0].Detail(), "The controller now seals with fpr_bbb.")
+		assert.Contains(t, diags[0].Detail(), "\n  - sealedsecret ns_aaa/name_aaa, sealed with fpr_aaa\n  - sealedsecret_bundle ns_aaa, sealed with fpr_aaa\n")
+		assert.Contains(t, diags[0].Detail(), "They are sealed again with the current key.")
+	}
+
+	// a resource planned again is reported once, one planned later reports all of them
+	diags = nil
+	addKeyRotationWarning(ctx, &diags, provider, "sealedsecret ns_aaa/name_aaa", rotation)
+	assert.Empty(t, diags)
+	addKeyRotationWarning(ctx, &diags, provider, "sealedsecret ns_aaa/name_bbb", rotation)
+	if assert.Len(t, diags, 1) {
+		assert.Equal(t, keyRotationSummary+": 4 resources", diags[0].Summary())
+	}
+}
@@ -0,0 +1,129 @@
+package provider
+
+import (
+	"context"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"maps"
+	"math/big"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/jifwin/terraform-provider-sealedsecret/internal/kubeseal"
+)
+
+const keyRotationSummary = "Sealed with a previous controller key"
+
+// rotationSettleTime is how long a resource sealed with a previous key waits for others before they are reported.
+const rotationSettleTime = 500 * time.Millisecond
+
+// staleResources collects the resources of one provider configuration that are sealed with a previous key, so one
+// warning lists all of them. Terraform plans every resource on its own and does not tell providers when the plan is
+// complete, so each resource waits for settle and the last one added reports them.
+type staleResources struct {
+	mu        sync.Mutex
+	settle    time.Duration
+	resources map[string]string
+	added     int
+}
+
+// add records resource with the fingerprint it was sealed with and returns all resources to report, or nil when
+// a resource added later reports them. A resource planned more than once is reported once.
+func (s *staleResources) add(ctx context.Context, resource, sealedWith string) map[string]string {
+	if s == nil {
+		return map[string]string{resource: sealedWith}
+	}
+	s.mu.Lock()
+	if _, ok := s.resources[resource]; ok {
+		s.mu.Unlock()
+		return nil
+	}
+	if s.resources == nil {
+		s.resources = make(map[string]string)
+	}
+	s.resources[resource] = sealedWith
+	s.added++
+	added := s.added
+	s.mu.Unlock()
+
+	select {
+	case <-time.After(s.settle):
+	case <-ctx.Done():
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.added != added {
+		return nil
+	}
+	return maps.Clone(s.resources)
+}
+
+// keyRotation compares the key a resource was sealed with to the key the controller seals with now.
+type keyRotation struct {
+	stale      bool
+	sealedWith string
+	current    string
+}
+
+// checkKeyRotation fetches the controller's current key and compares it to the public_key stored for a resource.
+func checkKeyRotation(ctx context.Context, provider *ProviderConfig, used types.String) (keyRotation, diag.Diagnostics) {
+	pk, diags := planPublicKey(ctx, provider)
+	if diags.HasError() {
+		return keyRotation{}, diags
+	}
+	rotation := keyRotation{current: kubeseal.Fingerprint(pk)}
+	if formatPublicKeyAsString(pk) == used.ValueString() {
+		rotation.sealedWith = rotation.current
+		return rotation, diags
+	}
+	rotation.stale = true
+	var err error
+	if rotation.sealedWith, err = storedKeyFingerprint(used.ValueString()); err != nil {
+		rotation.sealedWith = "an unknown key"
+	}
+	return rotation, diags
+}
+
+// addKeyRotationWarning records a resource sealed with a previous key. The last one planned with the provider
+// configuration adds a single warning listing all of them and whether they are sealed again.
+func addKeyRotationWarning(ctx context.Context, diags *diag.Diagnostics, provider *ProviderConfig, resource string, rotation keyRotation) {
+	stale := provider.stale.add(ctx, resource, rotation.sealedWith)
+	if stale == nil {
+		return
+	}
+	names := slices.Sorted(maps.Keys(stale))
+	count := fmt.Sprintf("%d resource", len(names))
+	if len(names) > 1 {
+		count += "s"
+	}
+
+	var detail strings.Builder
+	fmt.Fprintf(&detail, "The controller now seals with %s. Resources of this provider configuration sealed with a previous key:\n", rotation.current)
+	for _, name := range names {
+		fmt.Fprintf(&detail, "\n  - %s, sealed with %s", name, stale[name])
+	}
+	if provider.ResealOnKeyRotation {
+		detail.WriteString("\n\nThey are sealed again with the current key.")
+	} else {
+		detail.WriteString("\n\nThe controller can still unseal them, they are marked with needs_reseal until they are replaced" +
+			" (reseal_on_key_rotation is disabled).")
+	}
+	diags.AddWarning(keyRotationSummary+": "+count, detail.String())
+}
+
+// storedKeyFingerprint returns the fingerprint of a public_key written by formatPublicKeyAsString.
+func storedKeyFingerprint(stored string) (string, error) {
+	n, e, ok := strings.Cut(stored, "::")
+	modulus, isNumber := new(big.Int).SetString(n, 10)
+	exponent, err := strconv.Atoi(e)
+	if !ok || !isNumber || err != nil {
+		return "", errors.New("unexpected public key format")
+	}
+	return kubeseal.Fingerprint(&rsa.PublicKey{N: modulus, E: exponent}), nil
+}
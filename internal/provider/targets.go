@@ -0,0 +1,130 @@
+package provider
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// secretID identifies the Secret a resource seals as <namespace>/<name>, with the controller appended when
+// multiple_controllers is set.
+func secretID(provider *ProviderConfig, namespace, name types.String) types.String {
+	if namespace.IsUnknown() || name.IsUnknown() {
+		return types.StringUnknown()
+	}
+	return types.StringValue(namespace.ValueString() + "/" + name.ValueString() + controllerSuffix(provider))
+}
+
+// bundleID identifies a bundle by its namespace, with the controller appended when multiple_controllers is set.
+func bundleID(provider *ProviderConfig, namespace types.String) types.String {
+	if namespace.IsUnknown() {
+		return types.StringUnknown()
+	}
+	return types.StringValue(namespace.ValueString() + controllerSuffix(provider))
+}
+
+// controllerSuffix keeps the ids of provider aliases for different controllers apart. Each alias runs in its own
+// provider process, so whether there are several controllers has to be configured.
+func controllerSuffix(provider *ProviderConfig) string {
+	if provider == nil || !provider.MultipleControllers {
+		return ""
+	}
+	return "@" + provider.ControllerNamespace + "/" + provider.ControllerName
+}
+
+// claimKind is what a resource does with a Secret. Resources of different kinds may share a Secret, like a
+// sealedsecret_in_cluster applying the manifest of a sealedsecret resource.
+type claimKind struct {
+	verb        string
+	consequence string
+}
+
+var (
+	sealingClaim  = claimKind{"sealed", "The controller unseals whichever SealedSecret is applied last into it."}
+	applyingClaim = claimKind{"applied", "Every apply overwrites the SealedSecret the other resource applied."}
+	mergingClaim  = claimKind{"merged", "The controller unseals whichever merged SealedSecret is applied last into it."}
+)
+
+// claimTokenKey is the private state key of the token identifying a resource instance in claims.
+const claimTokenKey = "claim_token"
+
+// privateState is the private state of a planned resource.
+type privateState interface {
+	GetKey(ctx context.Context, key string) ([]byte, diag.Diagnostics)
+	SetKey(ctx context.Context, key string, value []byte) diag.Diagnostics
+}
+
+// claimToken returns the random token identifying the planned resource instance, creating it on its first plan.
+// The framework does not expose resource addresses, so the token is kept in the private state, which Terraform
+// passes back when it plans the create half of a replacement.
+func claimToken(ctx context.Context, private privateState, diags *diag.Diagnostics) string {
+	stored, d := private.GetKey(ctx, claimTokenKey)
+	diags.Append(d...)
+	var token string
+	if len(stored) > 0 && json.Unmarshal(stored, &token) == nil && token != "" {
+		return token
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		diags.AddError("Unable to identify the resource", err.Error())
+		return ""
+	}
+	token = hex.EncodeToString(b)
+	value, err := json.Marshal(token)
+	if err != nil {
+		diags.AddError("Unable to identify the resource", err.Error())
+		return ""
+	}
+	diags.Append(private.SetKey(ctx, claimTokenKey, value)...)
+	return token
+}
+
+// secretTargets records the Secrets planned with one provider configuration. Terraform configures the provider for
+// every plan and apply, so two claims of the same Secret come from two resources of the same configuration.
+type secretTargets struct {
+	mu      sync.Mutex
+	claimed map[string]claimant
+}
+
+// claimant is a resource instance using a Secret, identified by its claim token.
+type claimant struct {
+	resource string
+	token    string
+}
+
+// claim records that the resource instance identified by token uses the Secret and warns when another instance
+// already does. Identical configurations of two resources still conflict, only the same instance planned again
+// does not.
+func (t *secretTargets) claim(diags *diag.Diagnostics, kind claimKind, namespace, name, resource, token string) {
+	if t == nil || namespace == "" || name == "" || token == "" {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.claimed == nil {
+		t.claimed = make(map[string]claimant)
+	}
+	target := namespace + "/" + name
+	key := kind.verb + " " + target
+	if other, ok := t.claimed[key]; ok {
+		if other.token == token {
+			return
+		}
+		by := other.resource + " and by " + resource
+		if other.resource == resource {
+			by = "more than one " + strings.TrimPrefix(resource, "a ")
+		}
+		diags.AddWarning("Secret "+kind.verb+" by more than one resource", fmt.Sprintf("The Secret %s is %s by %s. %s",
+			target, kind.verb, by, kind.consequence))
+		return
+	}
+	t.claimed[key] = claimant{resource: resource, token: token}
+}
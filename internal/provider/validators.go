@@ -0,0 +1,149 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/jifwin/terraform-provider-sealedsecret/internal/k8s"
+)
+
+var _ validator.String = durationValidator{}
+
+var errNonPositiveDuration = errors.New("duration must be positive")
+
+// durationValidator checks that a string is a positive duration understood by time.ParseDuration.
+type durationValidator struct{}
+
+func (v durationValidator) Description(ctx context.Context) string {
+	return "value must be a positive duration, e.g. 30s or 5m"
+}
+
+func (v durationValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v durationValidator) ValidateString(ctx context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+	d, err := time.ParseDuration(req.ConfigValue.ValueString())
+	if err == nil && d <= 0 {
+		err = errNonPositiveDuration
+	}
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(req.Path, "Invalid duration", v.Description(ctx)+": "+err.Error())
+	}
+}
+
+var _ validator.String = helmValuesKeyValidator{}
+
+// helmValuesKeyValidator checks that a Helm values key path has no empty segments.
+type helmValuesKeyValidator struct{}
+
+func (v helmValuesKeyValidator) Description(ctx context.Context) string {
+	return "value must be a dot separated key path without empty keys, e.g. sealedSecret.encryptedData"
+}
+
+func (v helmValuesKeyValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v helmValuesKeyValidator) ValidateString(ctx context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+	for _, key := range strings.Split(req.ConfigValue.ValueString(), ".") {
+		if key == "" {
+			resp.Diagnostics.AddAttributeError(req.Path, "Invalid Helm values key", v.Description(ctx))
+			return
+		}
+	}
+}
+
+var (
+	_ validator.String = secretNameValidator{}
+	_ validator.String = namespaceValidator{}
+	_ validator.Map    = secretDataValidator{}
+)
+
+// secretNameValidator checks that a secret name is a DNS-1123 subdomain.
+type secretNameValidator struct{}
+
+func (v secretNameValidator) Description(ctx context.Context) string {
+	return "value must be a lowercase DNS-1123 subdomain, e.g. my-secret"
+}
+
+func (v secretNameValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v secretNameValidator) ValidateString(ctx context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+	if err := k8s.ValidateName(req.ConfigValue.ValueString()); err != nil {
+		resp.Diagnostics.AddAttributeError(req.Path, "Invalid secret name", err.Error())
+	}
+}
+
+// namespaceValidator checks that a namespace is a DNS-1123 label.
+type namespaceValidator struct{}
+
+func (v namespaceValidator) Description(ctx context.Context) string {
+	return "value must be a lowercase DNS-1123 label, e.g. my-namespace"
+}
+
+func (v namespaceValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v namespaceValidator) ValidateString(ctx context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+	if err := k8s.ValidateNamespace(req.ConfigValue.ValueString()); err != nil {
+		resp.Diagnostics.AddAttributeError(req.Path, "Invalid namespace", err.Error())
+	}
+}
+
+// secretDataValidator checks the keys of a map against the rules for Secret data keys and, unless keysOnly is set,
+// the size of its known values against the Secret size limit.
+type secretDataValidator struct {
+	keysOnly bool
+}
+
+func (v secretDataValidator) Description(ctx context.Context) string {
+	if v.keysOnly {
+		return "keys must consist of alphanumeric characters, '-', '_' or '.'"
+	}
+	return "keys must consist of alphanumeric characters, '-', '_' or '.' and the values must not exceed 1 MiB in total"
+}
+
+func (v secretDataValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v secretDataValidator) ValidateMap(ctx context.Context, req validator.MapRequest, resp *validator.MapResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+	values := make(map[string]string, len(req.ConfigValue.Elements()))
+	for key, value := range req.ConfigValue.Elements() {
+		if err := k8s.ValidateKey(key); err != nil {
+			resp.Diagnostics.AddAttributeError(req.Path.AtMapKey(key), "Invalid secret key", err.Error())
+		}
+		if s, ok := value.(types.String); ok && !s.IsNull() && !s.IsUnknown() {
+			values[key] = s.ValueString()
+		}
+	}
+	if v.keysOnly {
+		return
+	}
+	if err := k8s.ValidateSize(values); err != nil {
+		resp.Diagnostics.AddAttributeError(req.Path, "Secret too large", err.Error())
+	}
+}
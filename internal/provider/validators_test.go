@@ -0,0 +1,72 @@
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/jifwin/terraform-provider-sealedsecret/internal/k8s"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecretDataValidator(t *testing.T) {
+	tests := []struct {
+		Name          string
+		Validator     secretDataValidator
+		Data          map[string]attr.Value
+		ExpectedPaths []path.Path
+	}{
+		{
+			Name:      "valid keys",
+			Validator: secretDataValidator{},
+			Data:      map[string]attr.Value{"tls.crt": types.StringValue("a"), "API_KEY": types.StringUnknown()},
+		},
+		{
+			Name:          "invalid key points at the map key",
+			Validator:     secretDataValidator{},
+			Data:          map[string]attr.Value{"a/b": types.StringValue("a")},
+			ExpectedPaths: []path.Path{path.Root(data).AtMapKey("a/b")},
+		},
+		{
+			Name:          "too large",
+			Validator:     secretDataValidator{},
+			Data:          map[string]attr.Value{"a": types.StringValue(strings.Repeat("x", k8s.MaxSecretSize+1))},
+			ExpectedPaths: []path.Path{path.Root(data)},
+		},
+		{
+			Name:      "size is not checked for keys only",
+			Validator: secretDataValidator{keysOnly: true},
+			Data:      map[string]attr.Value{"a": types.StringValue(strings.Repeat("x", k8s.MaxSecretSize+1))},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			resp := validator.MapResponse{}
+			tc.Validator.ValidateMap(context.Background(), validator.MapRequest{
+				Path:        path.Root(data),
+				ConfigValue: types.MapValueMust(types.StringType, tc.Data),
+			}, &resp)
+			var paths []path.Path
+			for _, d := range resp.Diagnostics.Errors() {
+				paths = append(paths, d.(diag.DiagnosticWithPath).Path())
+			}
+			assert.Equal(t, tc.ExpectedPaths, paths)
+		})
+	}
+}
+
+func TestNameValidators(t *testing.T) {
+	resp := validator.StringResponse{}
+	secretNameValidator{}.ValidateString(context.Background(), validator.StringRequest{Path: path.Root(name), ConfigValue: types.StringValue("Name_AAA")}, &resp)
+	assert.True(t, resp.Diagnostics.HasError())
+
+	resp = validator.StringResponse{}
+	namespaceValidator{}.ValidateString(context.Background(), validator.StringRequest{Path: path.Root(namespace), ConfigValue: types.StringValue("ns-aaa")}, &resp)
+	assert.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+}